@@ -0,0 +1,278 @@
+package shapes
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MaxAtlasSize is the maximum width and height in pixels of the
+// textures packed by NewTextureAtlas.
+var MaxAtlasSize = 2048
+
+// atlasPadding is the number of transparent pixels between the
+// images packed in an atlas, avoiding bleeding with linear filters.
+const atlasPadding = 1
+
+// TextureRegion is a rectangular area of a texture.
+type TextureRegion struct {
+	// Name of the region in its atlas
+	Name string
+
+	// Texture containing the region
+	Texture *Texture
+
+	// Top-left corner of the region in the texture, in pixels
+	X, Y int
+
+	// Size of the image stored in the region, in pixels
+	Width, Height int
+
+	// Rotated is true if the image is stored rotated by 90 degrees
+	// clockwise. The region is then Height pixels wide and Width
+	// pixels high in the texture.
+	Rotated bool
+}
+
+// NewTextureRegion returns the region of the texture with the given
+// top-left corner and size in pixels.
+func NewTextureRegion(texture *Texture, x, y, width, height int) *TextureRegion {
+	return &TextureRegion{Texture: texture, X: x, Y: y, Width: width, Height: height}
+}
+
+// TexCoords returns the texture coordinates of the bottom-left,
+// bottom-right, top-left and top-right corners of the image stored
+// in the region, the order of the vertices of a box.
+func (r *TextureRegion) TexCoords() []float32 {
+	tw, th := float32(r.Texture.Width()), float32(r.Texture.Height())
+	w, h := r.Width, r.Height
+	if r.Rotated {
+		w, h = h, w
+	}
+	// The t axis points up in the texture
+	s0, s1 := float32(r.X)/tw, float32(r.X+w)/tw
+	top, bottom := 1-float32(r.Y)/th, 1-float32(r.Y+h)/th
+	if r.Rotated {
+		// The left edge of the image is on the top of the
+		// region
+		return []float32{
+			s0, top,
+			s0, bottom,
+			s1, top,
+			s1, bottom,
+		}
+	}
+	return []float32{
+		s0, bottom,
+		s1, bottom,
+		s0, top,
+		s1, top,
+	}
+}
+
+// TextureAtlas is a texture containing many images, each one stored
+// in a named region.
+type TextureAtlas struct {
+	texture *Texture
+	regions map[string]*TextureRegion
+}
+
+// NewTextureAtlas packs the images in a single texture, in rows of
+// decreasing height. It returns an error if the images don't fit in
+// a texture of MaxAtlasSize pixels.
+func NewTextureAtlas(images map[string]image.Image, options TextureOptions) (*TextureAtlas, error) {
+	names := make([]string, 0, len(images))
+	area, widest := 0, 0
+	for name, img := range images {
+		names = append(names, name)
+		size := img.Bounds().Size()
+		area += (size.X + atlasPadding) * (size.Y + atlasPadding)
+		if size.X+atlasPadding > widest {
+			widest = size.X + atlasPadding
+		}
+	}
+	// Tallest images first, by name for a stable layout
+	sort.Slice(names, func(i, j int) bool {
+		hi, hj := images[names[i]].Bounds().Dy(), images[names[j]].Bounds().Dy()
+		if hi != hj {
+			return hi > hj
+		}
+		return names[i] < names[j]
+	})
+
+	// Start from the smallest power-of-two square holding the
+	// images and double the width until they fit
+	width := 1
+	for width*width < area || width < widest {
+		width *= 2
+	}
+	for ; width <= MaxAtlasSize; width *= 2 {
+		rects, height := packRows(names, images, width)
+		size := 1
+		for size < height {
+			size *= 2
+		}
+		if size > MaxAtlasSize {
+			continue
+		}
+		sheet := image.NewNRGBA(image.Rect(0, 0, width, size))
+		atlas := &TextureAtlas{regions: make(map[string]*TextureRegion)}
+		for i, name := range names {
+			img := images[name]
+			draw.Draw(sheet, rects[i], img, img.Bounds().Min, draw.Src)
+		}
+		texture, err := NewTexture(sheet, options)
+		if err != nil {
+			return nil, err
+		}
+		atlas.texture = texture
+		for i, name := range names {
+			r := rects[i]
+			atlas.regions[name] = &TextureRegion{
+				Name:    name,
+				Texture: atlas.texture,
+				X:       r.Min.X,
+				Y:       r.Min.Y,
+				Width:   r.Dx(),
+				Height:  r.Dy(),
+			}
+		}
+		return atlas, nil
+	}
+	return nil, fmt.Errorf("images don't fit in a %dx%d atlas", MaxAtlasSize, MaxAtlasSize)
+}
+
+// packRows places the images in rows of the given width and returns
+// their rectangles and the total height.
+func packRows(names []string, images map[string]image.Image, width int) ([]image.Rectangle, int) {
+	rects := make([]image.Rectangle, len(names))
+	x, y, rowHeight := 0, 0, 0
+	for i, name := range names {
+		size := images[name].Bounds().Size()
+		if x+size.X > width {
+			x, y = 0, y+rowHeight+atlasPadding
+			rowHeight = 0
+		}
+		rects[i] = image.Rect(x, y, x+size.X, y+size.Y)
+		x += size.X + atlasPadding
+		if size.Y > rowHeight {
+			rowHeight = size.Y
+		}
+	}
+	return rects, y + rowHeight
+}
+
+// atlasFrame is a frame of a TexturePacker descriptor.
+type atlasFrame struct {
+	Filename string `json:"filename"`
+	Frame    struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+		W int `json:"w"`
+		H int `json:"h"`
+	} `json:"frame"`
+	Rotated bool `json:"rotated"`
+}
+
+// atlasDescriptor is a TexturePacker descriptor. Frames are either a
+// hash indexed by name or an array of named frames.
+type atlasDescriptor struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image string `json:"image"`
+	} `json:"meta"`
+}
+
+// LoadTextureAtlas returns the atlas of a sprite sheet already loaded
+// in texture. The regions are read from a TexturePacker JSON
+// descriptor, in the hash or array format. The frame sizes are the
+// sizes of the images, rotated images are stored rotated by 90 degrees
+// clockwise. Trimmed images are not restored to their original size.
+func LoadTextureAtlas(texture *Texture, descriptor io.Reader) (*TextureAtlas, error) {
+	var d atlasDescriptor
+	if err := json.NewDecoder(descriptor).Decode(&d); err != nil {
+		return nil, err
+	}
+	return newAtlasFromDescriptor(texture, &d)
+}
+
+// LoadTextureAtlasFile loads a sprite sheet described by the given
+// TexturePacker JSON file. The image of the sheet is loaded from the
+// path in the descriptor, relative to the descriptor itself.
+func LoadTextureAtlasFile(filename string, options TextureOptions) (*TextureAtlas, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var d atlasDescriptor
+	if err := json.NewDecoder(file).Decode(&d); err != nil {
+		return nil, err
+	}
+	if d.Meta.Image == "" {
+		return nil, fmt.Errorf("%s doesn't name the image of the sheet", filename)
+	}
+	texture, err := LoadTextureFile(filepath.Join(filepath.Dir(filename), d.Meta.Image), options)
+	if err != nil {
+		return nil, err
+	}
+	return newAtlasFromDescriptor(texture, &d)
+}
+
+func newAtlasFromDescriptor(texture *Texture, d *atlasDescriptor) (*TextureAtlas, error) {
+	var frames []atlasFrame
+	if len(d.Frames) > 0 && d.Frames[0] == '[' {
+		if err := json.Unmarshal(d.Frames, &frames); err != nil {
+			return nil, err
+		}
+	} else {
+		var hash map[string]atlasFrame
+		if err := json.Unmarshal(d.Frames, &hash); err != nil {
+			return nil, err
+		}
+		for name, f := range hash {
+			f.Filename = name
+			frames = append(frames, f)
+		}
+	}
+	atlas := &TextureAtlas{texture: texture, regions: make(map[string]*TextureRegion)}
+	for _, f := range frames {
+		region := &TextureRegion{
+			Name:    f.Filename,
+			Texture: texture,
+			X:       f.Frame.X,
+			Y:       f.Frame.Y,
+			Width:   f.Frame.W,
+			Height:  f.Frame.H,
+			Rotated: f.Rotated,
+		}
+		atlas.regions[f.Filename] = region
+	}
+	return atlas, nil
+}
+
+// Texture returns the texture containing the images of the atlas.
+func (a *TextureAtlas) Texture() *Texture {
+	return a.texture
+}
+
+// Region returns the region with the given name, nil if the atlas
+// doesn't contain it.
+func (a *TextureAtlas) Region(name string) *TextureRegion {
+	return a.regions[name]
+}
+
+// Names returns the names of the regions in alphabetical order.
+func (a *TextureAtlas) Names() []string {
+	names := make([]string, 0, len(a.regions))
+	for name := range a.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
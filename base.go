@@ -1,9 +1,11 @@
 package shapes
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 
+	"github.com/aded/shapes/internal/mathf"
 	"github.com/remogatto/mathgl"
 	"github.com/remogatto/shaders"
 )
@@ -13,19 +15,16 @@ type Base struct {
 	// Vertices of the generic shape
 	vertices []float32
 
-	// Matrices
-	projMatrix  mathgl.Mat4f
-	modelMatrix mathgl.Mat4f
-	viewMatrix  mathgl.Mat4f
+	// Primitive used to assemble the vertices
+	primitive Primitive
 
-	// Center of the shape
-	x, y float32
+	// Local transform and position in the scene graph
+	node
 
-	// Angle
-	angle float32
-
-	// Bounds
-	bounds image.Rectangle
+	// Axis-aligned bounds of the transformed vertices, computed
+	// lazily
+	aabb      Rect
+	aabbValid bool
 
 	// Color
 	color color.Color
@@ -36,60 +35,110 @@ type Base struct {
 	// Color matrix (four color component for each vertex)
 	vColor []float32
 
+	// Gradient filling the shape, nil for a uniform color
+	gradient Gradient
+
+	// True if the colors were set per vertex
+	vertexColored bool
+
+	// Geometry before the subdivision for the gradient, nil if
+	// the vertices are not subdivided
+	plain *geometry
+
 	// Texture
-	texBuffer uint32
+	texture   *Texture
 	texCoords []float32
 
+	// True if texture coordinates are generated from the
+	// bounding box
+	autoTexCoords bool
+
+	// Way the shape is combined with the background
+	blend BlendMode
+
 	// GLSL program
 	program shaders.Program
 
-	// GLSL variables IDs
-	colorId       uint32
-	posId         uint32
-	projMatrixId  uint32
-	modelMatrixId uint32
-	viewMatrixId  uint32
-	texInId       uint32
-	texRatioId    uint32
-	textureId     uint32
+	// Vertex buffer objects holding the attributes on the GPU
+	buffers Buffers
+
+	// Renderer used to draw the shape
+	renderer Renderer
 }
 
 // Rotates a shape by the given angle in degrees.
 func (b *Base) Rotate(angle float32) {
-	b.angle += angle
-	b.modelMatrix = mathgl.Translate3D(b.x, b.y, 0).Mul4(mathgl.HomogRotate3DZ(b.angle))
+	t := b.transform
+	t.Angle += angle
+	b.SetTransform(t)
 }
 
-// RotateAround rotates the shape around the given point, by the given
-// angle in degrees.
+// RotateAround rotates the shape around the given point, in the
+// coordinates of the parent, by the given angle in degrees.
 func (b *Base) RotateAround(x, y, angle float32) {
-	b.angle += angle
-	dx, dy := x-b.x, y-b.y
-	b.modelMatrix = mathgl.Translate3D(x, y, 0).Mul4(mathgl.HomogRotate3DZ(b.angle))
-	b.modelMatrix = b.modelMatrix.Mul4(mathgl.Translate3D(-dx, -dy, 0))
+	t := b.transform
+	dx, dy := rotate(t.X-x, t.Y-y, angle)
+	t.X, t.Y = x+dx, y+dy
+	t.Angle += angle
+	b.SetTransform(t)
 }
 
-// Scale scales the shape relative to its center, by the given
-// factors.
+// Scale scales the shape relative to its pivot, by the given
+// factors. Factors multiply the current scale.
 func (b *Base) Scale(sx, sy float32) {
-	b.modelMatrix = mathgl.Translate3D(b.x, b.y, 0).Mul4(mathgl.Scale3D(sx, sy, 1.0))
-
+	t := b.transform
+	t.ScaleX *= sx
+	t.ScaleY *= sy
+	b.SetTransform(t)
 }
 
-// Move moves the shape by dx, dy.
+// Move moves the shape by dx, dy in the coordinates of the parent.
 func (b *Base) Move(dx, dy float32) {
-	b.modelMatrix = b.modelMatrix.Mul4(mathgl.Translate3D(dx, dy, 0))
-	b.bounds = b.bounds.Add(image.Point{int(dx), int(dy)})
-	b.x, b.y = b.x+dx, b.y+dy
+	t := b.transform
+	t.X += dx
+	t.Y += dy
+	b.SetTransform(t)
 }
 
-// MoveTo moves the shape in x, y position.
+// MoveTo moves the center of the shape in x, y position.
 func (b *Base) MoveTo(x, y float32) {
-	b.modelMatrix = mathgl.Translate3D(x, y, 0)
-	dx := x - b.x
-	dy := y - b.y
-	b.x, b.y = x, y
-	b.bounds = b.bounds.Add(image.Point{int(dx), int(dy)})
+	cx, cy := b.Center()
+	b.Move(x-cx, y-cy)
+}
+
+// SetTransform sets the position, rotation, scale and pivot of the
+// shape relative to its parent. The matrices are rebuilt from them
+// before drawing.
+func (b *Base) SetTransform(t Transform) {
+	b.setTransform(t)
+	b.invalidateWorld()
+}
+
+func (b *Base) invalidateWorld() {
+	b.worldValid = false
+	b.aabbValid = false
+}
+
+func (b *Base) boundsIn(m mathgl.Mat4f) Rect {
+	return rectOf(transformPoints(m.Mul4(b.model()), b.vertices))
+}
+
+// SetPivot sets the pivot of the shape, in shape coordinates, without
+// moving the shape.
+func (b *Base) SetPivot(x, y float32) {
+	t := b.transform
+	px, py := t.Apply(x, y)
+	t.X, t.Y = px, py
+	t.PivotX, t.PivotY = x, y
+	b.SetTransform(t)
+}
+
+// resetTransform places the pivot of the shape in (x, y), with no
+// rotation and unit scale.
+func (b *Base) resetTransform(x, y float32) {
+	t := NewTransform()
+	t.X, t.Y = x, y
+	b.SetTransform(t)
 }
 
 // Vertices returns the vertices slice.
@@ -98,24 +147,104 @@ func (b *Base) Vertices() []float32 {
 }
 
 // Center returns the coordinates of the transformed center of the
-// shape.
+// shape, relative to its parent.
 func (b *Base) Center() (float32, float32) {
-	return b.x, b.y
+	return b.transform.Apply(0, 0)
 }
 
-// SetCenter sets the coordinates of the center of the shape.
+// SetCenter sets the coordinates of the center of the shape. It's
+// equivalent to MoveTo.
 func (b *Base) SetCenter(x, y float32) {
-	b.x, b.y = x, y
+	b.MoveTo(x, y)
 }
 
 // Angle returns the current angle of the shape in degrees.
 func (b *Base) Angle() float32 {
-	return b.angle
+	return b.transform.Angle
 }
 
-// Bounds returns the bounds of the shape as a Rectangle.
+// TransformedVertices returns the vertices transformed in world
+// coordinates.
+func (b *Base) TransformedVertices() []float32 {
+	return transformPoints(b.world(), b.vertices)
+}
+
+// Contains returns true if the point (x, y) lies inside one of the
+// triangles of the transformed shape.
+func (b *Base) Contains(x, y float32) bool {
+	if !b.AABB().Contains(x, y) {
+		return false
+	}
+	m := b.world()
+	if m.Det() == 0 {
+		return false
+	}
+	// Test the point in shape coordinates
+	p := m.Inv().Mul4x1(mathgl.Vec4f{x, y, 0, 1})
+	v := b.vertices
+	contains := false
+	eachTriangle(b.primitive, len(v)/2, func(i0, i1, i2 int) {
+		contains = contains || insideTriangle(p[0], p[1],
+			v[i0*2], v[i0*2+1],
+			v[i1*2], v[i1*2+1],
+			v[i2*2], v[i2*2+1],
+		)
+	})
+	return contains
+}
+
+// Hulls returns the convex hull of the transformed vertices.
+func (b *Base) Hulls() [][]float32 {
+	hull := convexHull(b.TransformedVertices())
+	if len(hull) == 0 {
+		return nil
+	}
+	return [][]float32{hull}
+}
+
+// triangleHulls returns the transformed triangles of the shape.
+func (b *Base) triangleHulls() [][]float32 {
+	v := b.TransformedVertices()
+	var hulls [][]float32
+	eachTriangle(b.primitive, len(v)/2, func(i0, i1, i2 int) {
+		hulls = append(hulls, []float32{
+			v[i0*2], v[i0*2+1],
+			v[i1*2], v[i1*2+1],
+			v[i2*2], v[i2*2+1],
+		})
+	})
+	return hulls
+}
+
+// AABB returns the axis-aligned bounding rectangle of the
+// transformed vertices.
+func (b *Base) AABB() Rect {
+	if !b.aabbValid {
+		b.aabb = rectOf(b.TransformedVertices())
+		b.aabbValid = true
+	}
+	return b.aabb
+}
+
+// OBB returns the bounding box of the shape oriented like the shape
+// itself.
+func (b *Base) OBB() OrientedBox {
+	local := rectOf(b.vertices)
+	x, y := b.toWorld(b.transform.Apply(local.Center()))
+	angle, sx, sy := b.worldRotationScale()
+	return OrientedBox{
+		X:          x,
+		Y:          y,
+		HalfWidth:  local.Dx() / 2 * mathf.Abs(sx),
+		HalfHeight: local.Dy() / 2 * mathf.Abs(sy),
+		Angle:      angle,
+	}
+}
+
+// Bounds returns the smallest Rectangle containing the bounds of the
+// shape.
 func (b *Base) Bounds() image.Rectangle {
-	return b.bounds
+	return b.AABB().Image()
 }
 
 // Color returns the color of the shape.
@@ -129,48 +258,270 @@ func (b *Base) NColor() [4]float32 {
 	return b.nColor
 }
 
-// SetColor sets the color of the shape.
+// SetColor sets the color of the shape. It replaces the gradient and
+// the vertex colors.
 func (s *Base) SetColor(c color.Color) {
-
 	s.color = c
+	s.nColor = normalize(c)
+	s.gradient = nil
+	s.applyFill()
+}
+
+// SetVertexColors sets the color of each vertex of the shape. Colors
+// are interpolated across the primitives. It returns an error if the
+// number of colors doesn't match the number of vertices. Vertex
+// colors are replaced by the color of the shape when the geometry is
+// rebuilt explicitly, e.g. by Ellipse.SetSegments.
+//
+// Vertex colors replace the gradient, and with it the subdivision of
+// the shape: the colors are matched to the vertices returned by
+// Vertices once the gradient is removed, not to the subdivided ones.
+func (b *Base) SetVertexColors(colors []color.Color) error {
+	b.restoreGeometry()
+	b.gradient = nil
+	if len(colors) != len(b.vertices)/2 {
+		b.applyFill()
+		return fmt.Errorf("expected %d vertex colors, one per vertex of the shape without gradient, got %d", len(b.vertices)/2, len(colors))
+	}
+	b.vColor = b.vColor[:0]
+	for _, c := range colors {
+		n := normalize(c)
+		b.vColor = append(b.vColor, n[0], n[1], n[2], n[3])
+	}
+	b.vertexColored = true
+	b.buffers.invalidate(colorBuffer)
+	return nil
+}
+
+// Gradient returns the gradient filling the shape, nil if the shape
+// has a uniform color.
+func (b *Base) Gradient() Gradient {
+	return b.gradient
+}
 
-	// Convert to RGBA
-	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
-	r, g, b, a := rgba.R, rgba.G, rgba.B, rgba.A
+// SetGradient fills the shape with the given gradient, in shape
+// coordinates. The triangles of the shape are subdivided as needed
+// to follow the gradient, and Vertices returns the subdivided
+// triangles. A nil gradient restores the color of the shape.
+func (b *Base) SetGradient(g Gradient) {
+	b.gradient = g
+	b.applyFill()
+}
 
-	// Normalize the color components
-	s.nColor = [4]float32{
-		float32(r) / 255,
-		float32(g) / 255,
-		float32(b) / 255,
-		float32(a) / 255,
+// geometry holds the vertices of a shape as built by the shape
+// itself.
+type geometry struct {
+	vertices  []float32
+	primitive Primitive
+	texCoords []float32
+}
+
+// restoreGeometry replaces the subdivided vertices with the ones
+// built by the shape.
+func (b *Base) restoreGeometry() {
+	if b.plain == nil {
+		return
 	}
+	b.vertices, b.primitive, b.texCoords = b.plain.vertices, b.plain.primitive, b.plain.texCoords
+	b.plain = nil
+	b.buffers.invalidate(allBuffers)
+}
 
-	// TODO improve code
-	vCount := len(s.vertices) / 2
-	s.vColor = s.vColor[:0]
+// applyFill computes the color of each vertex from the color or the
+// gradient of the shape. Shapes filled with a gradient are
+// subdivided in a list of triangles, lines are only colored.
+func (b *Base) applyFill() {
+	b.restoreGeometry()
+	b.vertexColored = false
+	if b.gradient != nil && b.primitive != Lines {
+		b.plain = &geometry{b.vertices, b.primitive, b.texCoords}
+		m := newMesh(b.primitive, b.vertices, b.texCoords)
+		b.gradient.subdivide(m)
+		b.vertices, b.primitive = m.vertices, Triangles
+		if len(b.texCoords) > 0 {
+			b.texCoords = m.texCoords
+		}
+		b.aabbValid = false
+		b.buffers.invalidate(allBuffers)
+	}
+	vCount := len(b.vertices) / 2
+	b.vColor = b.vColor[:0]
 	for i := 0; i < vCount; i++ {
-		s.vColor = append(s.vColor, s.nColor[0], s.nColor[1], s.nColor[2], s.nColor[3])
+		c := b.nColor
+		if b.gradient != nil {
+			c = gradientColor(b.gradient, b.vertices[i*2], b.vertices[i*2+1])
+		}
+		b.vColor = append(b.vColor, c[0], c[1], c[2], c[3])
 	}
+	b.buffers.invalidate(colorBuffer)
 }
 
-// AttachToWorld fills projection and view matrices with world's
-// matrices.
+// AttachToWorld attaches the shape to a world. The projection and
+// view matrices of the world are read each time the shape is drawn,
+// so the shape follows the changes of the world.
 func (b *Base) AttachToWorld(world World) {
-	b.projMatrix = world.Projection()
-	b.viewMatrix = world.View()
+	b.attached = world
+}
+
+// Texture returns the texture of the shape, nil if the shape is not
+// textured.
+func (b *Base) Texture() *Texture {
+	return b.texture
 }
 
-// SetTexture sets a texture for the shape. Texture argument is an
-// uint32 value returned by the OpenGL context. It's a client-code
-// responsibility to provide that value.
-func (b *Base) SetTexture(texture uint32, texCoords []float32) error {
+// SetTexture sets a texture for the shape. If texCoords is empty the
+// whole texture is mapped on the bounding box of the shape. A nil
+// texture removes the texture. It returns an error, leaving the shape
+// unchanged, if texCoords doesn't hold a pair of coordinates for each
+// vertex of the shape, without the subdivision of its gradient.
+func (b *Base) SetTexture(texture *Texture, texCoords []float32) error {
+	if texture == nil {
+		texCoords = nil
+	}
+	vertices := b.vertices
+	if b.plain != nil {
+		vertices = b.plain.vertices
+	}
+	if len(texCoords) > 0 && len(texCoords) != len(vertices) {
+		return fmt.Errorf("expected %d texture coordinates, got %d", len(vertices), len(texCoords))
+	}
+	b.restoreGeometry()
+	b.autoTexCoords = texture != nil && len(texCoords) == 0
+	if b.autoTexCoords {
+		texCoords = b.bboxTexCoords()
+	}
 	b.texCoords = texCoords
-	b.texBuffer = texture
+	b.texture = texture
+	b.buffers.invalidate(texCoordBuffer)
+	if b.gradient != nil {
+		b.applyFill()
+	}
 	return nil
 }
 
+// verticesChanged updates colors and generated texture coordinates
+// after the vertices of the shape have been replaced.
+func (b *Base) verticesChanged() {
+	b.plain = nil
+	b.aabbValid = false
+	b.buffers.invalidate(allBuffers)
+	if b.autoTexCoords {
+		b.texCoords = b.bboxTexCoords()
+	}
+	b.applyFill()
+	b.boundsChanged()
+}
+
+// BlendMode returns the way the shape is combined with the
+// background.
+func (b *Base) BlendMode() BlendMode {
+	return b.blend
+}
+
+// SetBlendMode sets the way the shape is combined with the
+// background. The default is NormalBlend.
+func (b *Base) SetBlendMode(mode BlendMode) {
+	b.blend = mode
+}
+
+// SetRenderer sets the renderer used to draw the shape. A nil
+// renderer restores DefaultRenderer.
+func (b *Base) SetRenderer(renderer Renderer) {
+	b.renderer = renderer
+}
+
+// Renderer returns the renderer used to draw the shape.
+func (b *Base) Renderer() Renderer {
+	if b.renderer == nil {
+		return DefaultRenderer
+	}
+	return b.renderer
+}
+
+// render hands the geometry of the shape to its renderer.
+func (b *Base) render() {
+	cmd := &DrawCommand{
+		Program:   b.program,
+		Primitive: b.primitive,
+		Vertices:  b.vertices,
+		Colors:    b.vColor,
+		Model:     b.world(),
+		Opacity:   b.worldOpacity(),
+		Blend:     b.blend,
+		Buffers:   &b.buffers,
+	}
+	if b.attached != nil {
+		cmd.Projection = b.attached.Projection()
+		cmd.View = b.attached.View()
+	}
+	if b.texture != nil && len(b.texCoords) > 0 {
+		cmd.TexCoords = b.texCoords
+		cmd.Texture = b.texture
+	}
+	b.Renderer().Render(cmd)
+}
+
+// Release deletes the OpenGL resources allocated by the renderer for
+// the shape. The texture is owned by the client code and it's not
+// deleted.
+func (b *Base) Release() {
+	b.buffers.Release()
+}
+
+// cloneInto copies the geometry, the transform, the z-index, the
+// color, the gradient, the opacity, the blend mode, the texture, the
+// world and the renderer of the shape into c. The copy doesn't belong
+// to any group and doesn't share the GPU buffers.
+func (b *Base) cloneInto(c *Base) {
+	c.vertices = append([]float32(nil), b.vertices...)
+	c.primitive = b.primitive
+	c.program = b.program
+	c.SetTransform(b.transform)
+	c.zIndex = b.zIndex
+	c.transparency, c.blend = b.transparency, b.blend
+	c.color, c.nColor = b.color, b.nColor
+	c.vColor = append([]float32(nil), b.vColor...)
+	c.gradient, c.vertexColored = b.gradient, b.vertexColored
+	if b.plain != nil {
+		c.plain = &geometry{
+			append([]float32(nil), b.plain.vertices...),
+			b.plain.primitive,
+			append([]float32(nil), b.plain.texCoords...),
+		}
+	}
+	c.texture = b.texture
+	c.texCoords = append([]float32(nil), b.texCoords...)
+	c.autoTexCoords = b.autoTexCoords
+	c.attached = b.attached
+	c.renderer = b.renderer
+}
+
+// bboxTexCoords returns texture coordinates mapping the whole
+// texture on the bounding box of the vertices.
+func (b *Base) bboxTexCoords() []float32 {
+	if len(b.vertices) < 2 {
+		return nil
+	}
+	minX, minY := b.vertices[0], b.vertices[1]
+	maxX, maxY := minX, minY
+	for i := 2; i+1 < len(b.vertices); i += 2 {
+		minX, maxX = mathf.Min(minX, b.vertices[i]), mathf.Max(maxX, b.vertices[i])
+		minY, maxY = mathf.Min(minY, b.vertices[i+1]), mathf.Max(maxY, b.vertices[i+1])
+	}
+	w, h := maxX-minX, maxY-minY
+	texCoords := make([]float32, len(b.vertices))
+	for i := 0; i+1 < len(b.vertices); i += 2 {
+		if w > 0 {
+			texCoords[i] = (b.vertices[i] - minX) / w
+		}
+		if h > 0 {
+			texCoords[i+1] = (b.vertices[i+1] - minY) / h
+		}
+	}
+	return texCoords
+}
+
 // String returns a string representation of the shape.
 func (b *Base) String() string {
-	return b.bounds.String()
+	return b.Bounds().String()
 }
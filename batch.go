@@ -0,0 +1,138 @@
+package shapes
+
+import "github.com/remogatto/mathgl"
+
+// Flusher is implemented by renderers buffering draw commands. Flush
+// submits the buffered commands.
+type Flusher interface {
+	Flush()
+}
+
+// BatchRenderer merges consecutive draw commands sharing the same
+// program, texture, matrices, opacity and blend mode in a single
+// command, submitted to the wrapped renderer. Vertices are
+// transformed by the model matrix on the CPU, so each batch is drawn
+// with an identity model matrix.
+//
+// Commands are buffered until a command that can't be merged is
+// rendered or Flush is called. Groups flush their renderer at the
+// end of Draw:
+//
+//	group.SetRenderer(shapes.NewBatchRenderer(shapes.DefaultRenderer))
+//	group.Draw() // one draw call per batch
+type BatchRenderer struct {
+	// target renders the batches
+	target Renderer
+
+	// batch accumulates the merged commands
+	batch DrawCommand
+
+	// pending is true if batch contains vertices to render
+	pending bool
+}
+
+// NewBatchRenderer returns a renderer batching the commands drawn
+// by target.
+func NewBatchRenderer(target Renderer) *BatchRenderer {
+	return &BatchRenderer{target: target}
+}
+
+// Render adds the command to the current batch. The batch is
+// flushed first if the command can't be merged with it.
+func (r *BatchRenderer) Render(cmd *DrawCommand) {
+	if len(cmd.Vertices) == 0 {
+		return
+	}
+	if r.pending && !r.mergeable(cmd) {
+		r.Flush()
+	}
+	if !r.pending {
+		r.batch.Program = cmd.Program
+		r.batch.Primitive = Triangles
+		if cmd.Primitive == Lines {
+			r.batch.Primitive = Lines
+		}
+		r.batch.Texture = cmd.Texture
+		r.batch.Model = mathgl.Ident4f()
+		r.batch.Projection = cmd.Projection
+		r.batch.View = cmd.View
+		r.batch.Opacity = cmd.Opacity
+		r.batch.Blend = cmd.Blend
+		r.pending = true
+	}
+
+	// Transform the vertices to world coordinates
+	count := len(cmd.Vertices) / 2
+	vertices := make([]float32, count*2)
+	for i := 0; i < count; i++ {
+		p := cmd.Model.Mul4x1(mathgl.Vec4f{cmd.Vertices[i*2], cmd.Vertices[i*2+1], 0, 1})
+		vertices[i*2], vertices[i*2+1] = p[0], p[1]
+	}
+
+	add := func(i int) {
+		r.batch.Vertices = append(r.batch.Vertices, vertices[i*2], vertices[i*2+1])
+		r.batch.Colors = append(r.batch.Colors, cmd.Colors[i*4:i*4+4]...)
+		if len(cmd.TexCoords) > 0 {
+			r.batch.TexCoords = append(r.batch.TexCoords, cmd.TexCoords[i*2], cmd.TexCoords[i*2+1])
+		}
+	}
+	if cmd.Primitive == Lines {
+		for i := 0; i+1 < count; i += 2 {
+			add(i)
+			add(i + 1)
+		}
+		return
+	}
+	// Strips and fans can't be concatenated, batch them as
+	// separate triangles.
+	eachTriangle(cmd.Primitive, count, func(i0, i1, i2 int) {
+		add(i0)
+		add(i1)
+		add(i2)
+	})
+}
+
+// mergeable returns true if the command can be added to the current
+// batch.
+func (r *BatchRenderer) mergeable(cmd *DrawCommand) bool {
+	textured := len(cmd.TexCoords) > 0
+	return cmd.Program == r.batch.Program &&
+		(cmd.Primitive == Lines) == (r.batch.Primitive == Lines) &&
+		textured == (len(r.batch.TexCoords) > 0) &&
+		(!textured || cmd.Texture == r.batch.Texture) &&
+		cmd.Projection == r.batch.Projection &&
+		cmd.View == r.batch.View &&
+		cmd.Opacity == r.batch.Opacity &&
+		cmd.Blend == r.batch.Blend
+}
+
+// Flush renders the current batch with the wrapped renderer.
+func (r *BatchRenderer) Flush() {
+	if !r.pending {
+		return
+	}
+	r.target.Render(&r.batch)
+	r.pending = false
+
+	// Reuse the buffers for the next batch
+	r.batch.Vertices = r.batch.Vertices[:0]
+	r.batch.Colors = r.batch.Colors[:0]
+	r.batch.TexCoords = r.batch.TexCoords[:0]
+}
+
+// Begin calls Begin on the wrapped renderer, if it saves the state of
+// the context.
+func (r *BatchRenderer) Begin() {
+	if s, ok := r.target.(StateRestorer); ok {
+		s.Begin()
+	}
+}
+
+// End flushes the current batch and calls End on the wrapped
+// renderer, if it saves the state of the context.
+func (r *BatchRenderer) End() {
+	r.Flush()
+	if s, ok := r.target.(StateRestorer); ok {
+		s.End()
+	}
+}
@@ -0,0 +1,88 @@
+package shapes
+
+import (
+	"reflect"
+	"testing"
+)
+
+const benchShapes = 500
+
+// newBenchGroup returns a group of boxes drawn by the given
+// renderer.
+func newBenchGroup(renderer Renderer) *Group {
+	group := NewGroup()
+	group.SetRenderer(renderer)
+	for i := 0; i < benchShapes; i++ {
+		box := NewBox(0, 8, 8)
+		box.AttachToWorld(testWorld{})
+		box.MoveTo(float32(i%32*10), float32(i/32*10-240))
+		box.Rotate(float32(i))
+		group.Append(box)
+	}
+	return group
+}
+
+// countingRenderer counts the commands it renders.
+type countingRenderer struct {
+	Renderer
+	calls int
+}
+
+func (r *countingRenderer) Render(cmd *DrawCommand) {
+	r.calls++
+	r.Renderer.Render(cmd)
+}
+
+func BenchmarkGroupDraw(b *testing.B) {
+	renderer := &countingRenderer{Renderer: NewSoftwareRenderer(320, 480)}
+	group := newBenchGroup(renderer)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		group.Draw()
+	}
+	b.ReportMetric(float64(renderer.calls)/float64(b.N), "calls/op")
+}
+
+func BenchmarkGroupDrawBatched(b *testing.B) {
+	renderer := &countingRenderer{Renderer: NewSoftwareRenderer(320, 480)}
+	group := newBenchGroup(NewBatchRenderer(renderer))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		group.Draw()
+	}
+	b.ReportMetric(float64(renderer.calls)/float64(b.N), "calls/op")
+}
+
+// stateRenderer records the commands it renders and its Begin and End
+// calls.
+type stateRenderer struct {
+	events []string
+}
+
+func (r *stateRenderer) Render(cmd *DrawCommand) { r.events = append(r.events, "render") }
+func (r *stateRenderer) Begin()                  { r.events = append(r.events, "begin") }
+func (r *stateRenderer) End()                    { r.events = append(r.events, "end") }
+
+func TestGroupDrawRestoresState(t *testing.T) {
+	renderer := new(stateRenderer)
+	inner := NewGroup()
+	inner.Append(NewBox(0, 8, 8))
+	group := NewGroup()
+	group.Append(NewBox(0, 8, 8))
+	group.Append(inner)
+	group.SetRenderer(renderer)
+	group.Draw()
+	expected := []string{"begin", "render", "begin", "render", "end", "end"}
+	if !reflect.DeepEqual(renderer.events, expected) {
+		t.Errorf("expected %v, got %v", expected, renderer.events)
+	}
+
+	// Batches are flushed before the state is restored
+	renderer.events = nil
+	group.SetRenderer(NewBatchRenderer(renderer))
+	group.Draw()
+	expected = []string{"begin", "begin", "render", "end", "end"}
+	if !reflect.DeepEqual(renderer.events, expected) {
+		t.Errorf("expected %v, got %v", expected, renderer.events)
+	}
+}
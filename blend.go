@@ -0,0 +1,74 @@
+package shapes
+
+import gl "github.com/remogatto/opengles2"
+
+// BlendMode is the way the colors of a shape are combined with the
+// colors already drawn.
+type BlendMode int
+
+const (
+	// NormalBlend draws the shape over the background, mixing
+	// them by the alpha of the shape.
+	NormalBlend BlendMode = iota
+
+	// AdditiveBlend adds the colors of the shape to the
+	// background, brightening it.
+	AdditiveBlend
+
+	// MultiplyBlend multiplies the background by the colors of
+	// the shape, darkening it.
+	MultiplyBlend
+
+	// ScreenBlend multiplies the inverse of the background by the
+	// inverse of the colors of the shape, brightening it.
+	ScreenBlend
+
+	// PremultipliedBlend draws the shape over the background as
+	// NormalBlend, for textures whose colors are already
+	// multiplied by alpha. Textures created with the
+	// PremultipliedAlpha option are always blended this way.
+	PremultipliedBlend
+)
+
+// glBlendFactors are the source and destination factors of each
+// blend mode. The default shaders output colors premultiplied by
+// alpha and opacity.
+var glBlendFactors = map[BlendMode][2]gl.Enum{
+	NormalBlend:        {gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+	AdditiveBlend:      {gl.ONE, gl.ONE},
+	MultiplyBlend:      {gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA},
+	ScreenBlend:        {gl.ONE, gl.ONE_MINUS_SRC_COLOR},
+	PremultipliedBlend: {gl.ONE, gl.ONE_MINUS_SRC_ALPHA},
+}
+
+// blend combines the premultiplied source color src with the
+// premultiplied destination color dst, as OpenGL does with the
+// factors of the mode.
+func blend(mode BlendMode, src, dst [4]float32) [4]float32 {
+	var out [4]float32
+	for i := range out {
+		switch mode {
+		case AdditiveBlend:
+			out[i] = src[i] + dst[i]
+		case MultiplyBlend:
+			out[i] = src[i]*dst[i] + dst[i]*(1-src[3])
+		case ScreenBlend:
+			out[i] = src[i] + dst[i]*(1-src[i])
+		default:
+			out[i] = src[i] + dst[i]*(1-src[3])
+		}
+	}
+	return out
+}
+
+// premultiply returns the color c multiplied by its alpha, unless it
+// is already premultiplied, and by the opacity.
+func premultiply(c [4]float32, premultiplied bool, opacity float32) [4]float32 {
+	if !premultiplied {
+		c[0], c[1], c[2] = c[0]*c[3], c[1]*c[3], c[2]*c[3]
+	}
+	for i := range c {
+		c[i] *= opacity
+	}
+	return c
+}
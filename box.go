@@ -1,10 +1,8 @@
 package shapes
 
 import (
-	"image"
+	"fmt"
 
-	"github.com/remogatto/mathgl"
-	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shaders"
 )
 
@@ -22,7 +20,7 @@ var (
                  uniform mat4 projection;
                  uniform mat4 view;
                  void main() {
-                     gl_Position = projection*model*view*pos;
+                     gl_Position = projection*view*model*pos;
                      vColor = color;
                      texOut = texIn;
                  }`)
@@ -35,17 +33,24 @@ var (
 	         varying vec2 texOut;
                  uniform sampler2D texture;
                  uniform float texRatio;
+                 uniform float opacity;
+                 uniform float premultiplied;
                  void main() {
                      vec2 flippedTexCoords = vec2(texOut.x, 1.0 - texOut.y);
-                     vec4 texColor = texture2D(texture, flippedTexCoords) * texRatio;
-                     vec4 vertColor = vColor * (1.0 - texRatio);
-                     gl_FragColor = texColor + vertColor;
+                     vec4 texColor = texture2D(texture, flippedTexCoords);
+                     texColor.rgb *= mix(texColor.a, 1.0, premultiplied);
+                     vec4 vertColor = vec4(vColor.rgb * vColor.a, vColor.a);
+                     vec4 color = texColor * texRatio + vertColor * (1.0 - texRatio);
+                     gl_FragColor = color * opacity;
                  }`)
 )
 
 // Box represents a box shape.
 type Box struct {
 	Base
+
+	// Size of the box
+	width, height float32
 }
 
 // NewBox creates a new box of given sizes. It takes as arguments a
@@ -53,6 +58,8 @@ type Box struct {
 func NewBox(program shaders.Program, width, height float32) *Box {
 
 	box := new(Box)
+	box.width, box.height = width, height
+	box.primitive = TriangleStrip
 
 	// The box is built around its center at (0, 0)
 	box.vertices = []float32{
@@ -66,66 +73,32 @@ func NewBox(program shaders.Program, width, height float32) *Box {
 	box.SetColor(DefaultColor)
 
 	box.program = program
-	box.program.Use()
-
-	// Get variables IDs from shaders
-	box.posId = program.GetAttribute("pos")
-	box.colorId = program.GetAttribute("color")
-	box.projMatrixId = program.GetUniform("projection")
-	box.modelMatrixId = program.GetUniform("model")
-	box.viewMatrixId = program.GetUniform("view")
-	box.texInId = program.GetAttribute("texIn")
-	box.textureId = program.GetUniform("texture")
-	box.texRatioId = program.GetUniform("texRatio")
-
-	// Fill the model matrix with the identity.
-	box.modelMatrix = mathgl.Ident4f()
-
-	// Create the bounding rectangle for the shape.
-	box.bounds = image.Rect(
-		int(-width/2), int(-height/2),
-		int(width/2), int(height/2),
-	)
+
+	// Place the box in the origin.
+	box.resetTransform(0, 0)
 
 	return box
 }
 
 // Draw actually renders the shape on the surface.
 func (box *Box) Draw() {
-	box.program.Use()
-
-	gl.VertexAttribPointer(box.posId, 2, gl.FLOAT, false, 0, &box.vertices[0])
-	gl.EnableVertexAttribArray(box.posId)
-
-	gl.VertexAttribPointer(box.colorId, 4, gl.FLOAT, false, 0, &box.vColor[0])
-	gl.EnableVertexAttribArray(box.colorId)
-
-	gl.UniformMatrix4fv(int32(box.modelMatrixId), 1, false, (*float32)(&box.modelMatrix[0]))
-	gl.UniformMatrix4fv(int32(box.projMatrixId), 1, false, (*float32)(&box.projMatrix[0]))
-	gl.UniformMatrix4fv(int32(box.viewMatrixId), 1, false, (*float32)(&box.viewMatrix[0]))
-
-	gl.Uniform1f(int32(box.texRatioId), 0.0)
+	box.render()
+}
 
-	// Texture
-	if len(box.texCoords) > 0 {
-		gl.Uniform1f(int32(box.texRatioId), 1.0)
-		gl.VertexAttribPointer(box.texInId, 2, gl.FLOAT, false, 0, &box.texCoords[0])
-		gl.EnableVertexAttribArray(box.texInId)
-		gl.ActiveTexture(gl.TEXTURE0)
-		gl.BindTexture(gl.TEXTURE_2D, box.texBuffer)
-		gl.Uniform1i(int32(box.textureId), 0)
+// SetTextureRegion maps the image stored in the region on the box.
+// It returns an error if the region is nil, e.g. if it was looked up
+// by a missing name.
+func (box *Box) SetTextureRegion(region *TextureRegion) error {
+	if region == nil {
+		return fmt.Errorf("cannot map a nil texture region")
 	}
-
-	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
-
-	gl.Flush()
-	gl.Finish()
+	return box.SetTexture(region.Texture, region.TexCoords())
 }
 
-// Clone makes a copy of the shape.
+// Clone returns a deep copy of the box.
 func (box *Box) Clone() Shape {
-	b := NewBox(box.program, float32(box.bounds.Dx()), float32(box.bounds.Dy()))
-	b.SetColor(box.color)
-	b.SetTexture(box.texBuffer, box.texCoords)
-	return b
+	c := new(Box)
+	c.width, c.height = box.width, box.height
+	box.cloneInto(&c.Base)
+	return c
 }
@@ -0,0 +1,44 @@
+package shapes
+
+import gl "github.com/remogatto/opengles2"
+
+// bufferMask selects the vertex buffer objects of a shape.
+type bufferMask int
+
+const (
+	vertexBuffer bufferMask = 1 << iota
+	colorBuffer
+	texCoordBuffer
+
+	allBuffers = vertexBuffer | colorBuffer | texCoordBuffer
+)
+
+// Buffers holds the vertex buffer objects storing the attributes of
+// a shape on the GPU. Buffers are created and filled by GLRenderer
+// on the first draw and uploaded again only when the attributes
+// change. Renderers that don't use the GPU ignore them.
+type Buffers struct {
+	// OpenGL names of the buffers, 0 before the first upload
+	vertices, colors, texCoords uint32
+
+	// dirty selects the buffers to upload on the next draw
+	dirty bufferMask
+}
+
+// invalidate marks the given buffers for upload.
+func (b *Buffers) invalidate(mask bufferMask) {
+	b.dirty |= mask
+}
+
+// Release deletes the buffers from the OpenGL context. They are
+// created again if the shape is drawn afterwards. Release must be
+// called from the thread owning the context.
+func (b *Buffers) Release() {
+	for _, id := range []*uint32{&b.vertices, &b.colors, &b.texCoords} {
+		if *id != 0 {
+			gl.DeleteBuffers(1, id)
+			*id = 0
+		}
+	}
+	b.dirty = allBuffers
+}
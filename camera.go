@@ -0,0 +1,206 @@
+package shapes
+
+import (
+	"math"
+	"time"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
+
+// Camera2D is a World looking at a plane from a point, with a zoom
+// factor and a rotation. World coordinates are in pixels at zoom 1,
+// with the y axis pointing up, and the point the camera looks at is
+// drawn at the center of the viewport.
+type Camera2D struct {
+	// Point at the center of the viewport
+	x, y float32
+
+	zoom  float32
+	angle float32
+
+	// Viewport size in pixels, fractional when the pixels are
+	// virtual
+	width, height float32
+
+	// Shape followed by the camera and time taken to cover about
+	// two thirds of the distance from it
+	target    Shape
+	smoothing time.Duration
+
+	// Area the viewport is kept in
+	bounds    Rect
+	hasBounds bool
+}
+
+// NewCamera2D returns a camera looking at the origin with a viewport
+// of the given size in pixels.
+func NewCamera2D(width, height float32) *Camera2D {
+	return &Camera2D{zoom: 1, width: width, height: height}
+}
+
+// Position returns the point the camera looks at.
+func (c *Camera2D) Position() (float32, float32) {
+	return c.x, c.y
+}
+
+// MoveTo moves the camera to look at (x, y).
+func (c *Camera2D) MoveTo(x, y float32) {
+	c.x, c.y = x, y
+	c.clampToBounds()
+}
+
+// Move pans the camera by (dx, dy), in world coordinates.
+func (c *Camera2D) Move(dx, dy float32) {
+	c.MoveTo(c.x+dx, c.y+dy)
+}
+
+// Zoom returns the zoom factor of the camera.
+func (c *Camera2D) Zoom() float32 {
+	return c.zoom
+}
+
+// SetZoom sets the zoom factor of the camera. Factors greater than 1
+// magnify the world. Factors not greater than 0 are ignored.
+func (c *Camera2D) SetZoom(zoom float32) {
+	if zoom <= 0 {
+		return
+	}
+	c.zoom = zoom
+	c.clampToBounds()
+}
+
+// Angle returns the rotation of the camera in degrees.
+func (c *Camera2D) Angle() float32 {
+	return c.angle
+}
+
+// SetAngle rotates the camera to angle degrees. The world appears
+// rotated by the opposite angle.
+func (c *Camera2D) SetAngle(angle float32) {
+	c.angle = angle
+	c.clampToBounds()
+}
+
+// Rotate rotates the camera by angle degrees.
+func (c *Camera2D) Rotate(angle float32) {
+	c.SetAngle(c.angle + angle)
+}
+
+// Viewport returns the size in pixels of the viewport.
+func (c *Camera2D) Viewport() (float32, float32) {
+	return c.width, c.height
+}
+
+// SetViewport sets the size in pixels of the viewport, usually when
+// the window is resized.
+func (c *Camera2D) SetViewport(width, height float32) {
+	c.width, c.height = width, height
+	c.clampToBounds()
+}
+
+// Follow makes the camera follow the center of the target, in world
+// coordinates, at each Update. With no smoothing the camera jumps on
+// the target, otherwise it covers about two thirds of the distance
+// in the smoothing time. A nil target stops following.
+func (c *Camera2D) Follow(target Shape, smoothing time.Duration) {
+	c.target, c.smoothing = target, smoothing
+}
+
+// Target returns the shape followed by the camera.
+func (c *Camera2D) Target() Shape {
+	return c.target
+}
+
+// SetBounds keeps the visible area of the camera inside bounds. The
+// camera looks at the center of bounds along the axes where the
+// visible area is larger.
+func (c *Camera2D) SetBounds(bounds Rect) {
+	c.bounds, c.hasBounds = bounds, true
+	c.clampToBounds()
+}
+
+// ClearBounds lets the camera move freely.
+func (c *Camera2D) ClearBounds() {
+	c.hasBounds = false
+}
+
+// Bounds returns the area the camera is kept in and true, or false
+// if the camera moves freely.
+func (c *Camera2D) Bounds() (Rect, bool) {
+	return c.bounds, c.hasBounds
+}
+
+// Update moves the camera towards its target, if any, by the time
+// elapsed dt.
+func (c *Camera2D) Update(dt time.Duration) {
+	if c.target == nil {
+		return
+	}
+	tx, ty := c.target.AABB().Center()
+	if c.smoothing <= 0 {
+		c.MoveTo(tx, ty)
+		return
+	}
+	k := 1 - float32(math.Exp(-float64(dt)/float64(c.smoothing)))
+	c.MoveTo(c.x+(tx-c.x)*k, c.y+(ty-c.y)*k)
+}
+
+// Projection returns the orthographic projection of the viewport,
+// with the origin at its center.
+func (c *Camera2D) Projection() mathgl.Mat4f {
+	w, h := c.width/2, c.height/2
+	return mathgl.Ortho2D(-w, w, -h, h)
+}
+
+// View returns the matrix moving the point the camera looks at to
+// the origin, then rotating and zooming the world around it.
+func (c *Camera2D) View() mathgl.Mat4f {
+	return mathgl.Scale3D(c.zoom, c.zoom, 1).
+		Mul4(mathgl.HomogRotate3DZ(-c.angle)).
+		Mul4(mathgl.Translate3D(-c.x, -c.y, 0))
+}
+
+// ScreenToWorld converts the point (x, y), in pixels of the viewport
+// with the origin in the top-left corner, to world coordinates.
+func (c *Camera2D) ScreenToWorld(x, y float32) (float32, float32) {
+	x, y = (x-c.width/2)/c.zoom, (c.height/2-y)/c.zoom
+	x, y = rotate(x, y, c.angle)
+	return x + c.x, y + c.y
+}
+
+// WorldToScreen converts the point (x, y), in world coordinates, to
+// pixels of the viewport with the origin in the top-left corner.
+func (c *Camera2D) WorldToScreen(x, y float32) (float32, float32) {
+	x, y = rotate(x-c.x, y-c.y, -c.angle)
+	return x*c.zoom + c.width/2, c.height/2 - y*c.zoom
+}
+
+// clampToBounds moves the camera so that the bounding rectangle of
+// its visible area lies in the bounds.
+func (c *Camera2D) clampToBounds() {
+	if !c.hasBounds {
+		return
+	}
+	hw, hh := c.halfExtents()
+	c.x = clampAxis(c.x, hw, c.bounds.MinX, c.bounds.MaxX)
+	c.y = clampAxis(c.y, hh, c.bounds.MinY, c.bounds.MaxY)
+}
+
+// halfExtents returns half the size of the axis-aligned rectangle
+// containing the visible area, in world coordinates.
+func (c *Camera2D) halfExtents() (float32, float32) {
+	w, h := c.width/2/c.zoom, c.height/2/c.zoom
+	sin, cos := math.Sincos(float64(c.angle) * math.Pi / 180)
+	s, co := mathf.Abs(float32(sin)), mathf.Abs(float32(cos))
+	return w*co + h*s, w*s + h*co
+}
+
+// clampAxis clamps the center v of a segment of half length h in
+// [lo, hi], centering it if the segment is longer.
+func clampAxis(v, h, lo, hi float32) float32 {
+	if 2*h >= hi-lo {
+		return (lo + hi) / 2
+	}
+	return mathf.Clamp(v, lo+h, hi-h)
+}
@@ -0,0 +1,24 @@
+package shapes
+
+import "github.com/remogatto/shaders"
+
+// Circle represents a circle shape.
+type Circle struct {
+	Ellipse
+}
+
+// NewCircle creates a new circle of the given radius. It takes as
+// arguments a linked program and the radius.
+func NewCircle(program shaders.Program, radius float32) *Circle {
+	return &Circle{*NewEllipse(program, radius, radius)}
+}
+
+// Radius returns the radius of the circle.
+func (c *Circle) Radius() float32 {
+	return c.rx
+}
+
+// Clone returns a deep copy of the circle.
+func (c *Circle) Clone() Shape {
+	return &Circle{*c.Ellipse.clone()}
+}
@@ -0,0 +1,271 @@
+// Package collision detects intersections between shapes using the
+// separating axis theorem.
+//
+// Shapes are tested through the convex polygons returned by their
+// Hulls method, so rotated and scaled shapes, segments, concave
+// polygons and groups are all supported. When two shapes intersect
+// a Contact describes how to separate them:
+//
+//	if c, ok := collision.Collide(player, wall); ok {
+//		mtv := c.MTV()
+//		player.Move(mtv[0], mtv[1])
+//	}
+package collision
+
+import (
+	"math"
+
+	"github.com/aded/shapes"
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
+
+// Epsilon is the tolerance used when comparing coordinates.
+var Epsilon float32 = 1e-4
+
+// Contact describes the intersection between two shapes A and B.
+type Contact struct {
+	// Normal is the unit vector along which the shapes overlap
+	// the least, pointing from A to B.
+	Normal mathgl.Vec2f
+
+	// Depth is the overlap of the shapes along Normal.
+	Depth float32
+
+	// Points are the vertices of the region where the shapes
+	// intersect.
+	Points []mathgl.Vec2f
+}
+
+// MTV returns the minimum translation vector, the shortest
+// translation of A that separates it from B.
+func (c Contact) MTV() mathgl.Vec2f {
+	return mathgl.Vec2f{-c.Normal[0] * c.Depth, -c.Normal[1] * c.Depth}
+}
+
+// Overlaps returns true if the shapes a and b intersect. Touching
+// shapes intersect.
+func Overlaps(a, b shapes.Shape) bool {
+	if !a.AABB().Overlaps(b.AABB()) {
+		return false
+	}
+	hullsA, hullsB := a.Hulls(), b.Hulls()
+	for _, ha := range hullsA {
+		for _, hb := range hullsB {
+			if _, _, ok := sat(ha, hb); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Collide tests the shapes a and b for intersection. If they
+// intersect it returns true and the contact information. For shapes
+// made of more convex polygons, the normal and the depth are those
+// of the deepest pair of intersecting polygons while the points are
+// collected from all the pairs.
+func Collide(a, b shapes.Shape) (Contact, bool) {
+	var contact Contact
+	if !a.AABB().Overlaps(b.AABB()) {
+		return contact, false
+	}
+	found := false
+	hullsA, hullsB := a.Hulls(), b.Hulls()
+	for _, ha := range hullsA {
+		for _, hb := range hullsB {
+			normal, depth, ok := sat(ha, hb)
+			if !ok {
+				continue
+			}
+			if !found || depth > contact.Depth {
+				contact.Normal, contact.Depth = normal, depth
+			}
+			found = true
+			contact.Points = appendUnique(contact.Points, contactPoints(ha, hb)...)
+		}
+	}
+	return contact, found
+}
+
+// sat tests two convex polygons for intersection. It returns the
+// axis of minimum overlap, pointing from a to b, and the overlap
+// along it.
+func sat(a, b []float32) (mathgl.Vec2f, float32, bool) {
+	var normal mathgl.Vec2f
+	depth := float32(math.Inf(1))
+	if len(a) < 2 || len(b) < 2 {
+		return normal, 0, false
+	}
+	all := append(axes(a), axes(b)...)
+	if len(all) == 0 {
+		// Two points
+		ok := mathf.Abs(a[0]-b[0]) <= Epsilon && mathf.Abs(a[1]-b[1]) <= Epsilon
+		return normal, 0, ok
+	}
+	for _, axis := range all {
+		minA, maxA := project(a, axis)
+		minB, maxB := project(b, axis)
+		if maxA < minB-Epsilon || maxB < minA-Epsilon {
+			return normal, 0, false
+		}
+		overlap := mathf.Min(maxA-minB, maxB-minA)
+		if overlap < depth {
+			normal, depth = axis, overlap
+		}
+	}
+	// Orient the normal from a to b
+	ax, ay := mean(a)
+	bx, by := mean(b)
+	if (bx-ax)*normal[0]+(by-ay)*normal[1] < 0 {
+		normal = mathgl.Vec2f{-normal[0], -normal[1]}
+	}
+	return normal, mathf.Max(depth, 0), true
+}
+
+// axes returns the unit normals of the edges of the polygon. Segments
+// also contribute their direction, which separates collinear
+// segments.
+func axes(points []float32) []mathgl.Vec2f {
+	n := len(points) / 2
+	var result []mathgl.Vec2f
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		dx, dy := points[j*2]-points[i*2], points[j*2+1]-points[i*2+1]
+		l := float32(math.Hypot(float64(dx), float64(dy)))
+		if l == 0 {
+			continue
+		}
+		result = append(result, mathgl.Vec2f{-dy / l, dx / l})
+		if n == 2 {
+			result = append(result, mathgl.Vec2f{dx / l, dy / l})
+			break
+		}
+	}
+	return result
+}
+
+// project returns the interval covered by the polygon projected on
+// the axis.
+func project(points []float32, axis mathgl.Vec2f) (float32, float32) {
+	lo := float32(math.Inf(1))
+	hi := float32(math.Inf(-1))
+	for i := 0; i+1 < len(points); i += 2 {
+		d := points[i]*axis[0] + points[i+1]*axis[1]
+		lo, hi = mathf.Min(lo, d), mathf.Max(hi, d)
+	}
+	return lo, hi
+}
+
+// contactPoints returns the vertices of the intersection of two
+// convex polygons: the vertices of each polygon contained in the
+// other one and the intersections of their edges.
+func contactPoints(a, b []float32) []mathgl.Vec2f {
+	var points []mathgl.Vec2f
+	for i := 0; i+1 < len(a); i += 2 {
+		if inside(b, a[i], a[i+1]) {
+			points = appendUnique(points, mathgl.Vec2f{a[i], a[i+1]})
+		}
+	}
+	for i := 0; i+1 < len(b); i += 2 {
+		if inside(a, b[i], b[i+1]) {
+			points = appendUnique(points, mathgl.Vec2f{b[i], b[i+1]})
+		}
+	}
+	eachEdge(a, func(p1, p2 mathgl.Vec2f) {
+		eachEdge(b, func(q1, q2 mathgl.Vec2f) {
+			if p, ok := intersection(p1, p2, q1, q2); ok {
+				points = appendUnique(points, p)
+			}
+		})
+	})
+	return points
+}
+
+// eachEdge calls fn for each edge of the polygon. Segments have a
+// single edge.
+func eachEdge(points []float32, fn func(p1, p2 mathgl.Vec2f)) {
+	n := len(points) / 2
+	if n < 2 {
+		return
+	}
+	edges := n
+	if n == 2 {
+		edges = 1
+	}
+	for i := 0; i < edges; i++ {
+		j := (i + 1) % n
+		fn(mathgl.Vec2f{points[i*2], points[i*2+1]}, mathgl.Vec2f{points[j*2], points[j*2+1]})
+	}
+}
+
+// inside returns true if (x, y) lies inside or on the border of the
+// convex polygon, whatever its winding.
+func inside(points []float32, x, y float32) bool {
+	n := len(points) / 2
+	switch n {
+	case 0:
+		return false
+	case 1:
+		return mathf.Abs(points[0]-x) <= Epsilon && mathf.Abs(points[1]-y) <= Epsilon
+	case 2:
+		return mathf.SegmentDistance(x, y, points[0], points[1], points[2], points[3]) <= Epsilon
+	}
+	var sign float32
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		ex, ey := points[j*2]-points[i*2], points[j*2+1]-points[i*2+1]
+		c := ex*(y-points[i*2+1]) - ey*(x-points[i*2])
+		if mathf.Abs(c) <= Epsilon {
+			continue
+		}
+		if sign*c < 0 {
+			return false
+		}
+		sign = c
+	}
+	return true
+}
+
+// intersection returns the intersection of the segments (p1, p2) and
+// (q1, q2). Parallel segments don't intersect in a single point.
+func intersection(p1, p2, q1, q2 mathgl.Vec2f) (mathgl.Vec2f, bool) {
+	rx, ry := p2[0]-p1[0], p2[1]-p1[1]
+	sx, sy := q2[0]-q1[0], q2[1]-q1[1]
+	d := rx*sy - ry*sx
+	if d == 0 {
+		return mathgl.Vec2f{}, false
+	}
+	qx, qy := q1[0]-p1[0], q1[1]-p1[1]
+	t := (qx*sy - qy*sx) / d
+	u := (qx*ry - qy*rx) / d
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return mathgl.Vec2f{}, false
+	}
+	return mathgl.Vec2f{p1[0] + rx*t, p1[1] + ry*t}, true
+}
+
+// appendUnique appends the points not already in the slice.
+func appendUnique(points []mathgl.Vec2f, more ...mathgl.Vec2f) []mathgl.Vec2f {
+next:
+	for _, p := range more {
+		for _, q := range points {
+			if mathf.Abs(p[0]-q[0]) <= Epsilon && mathf.Abs(p[1]-q[1]) <= Epsilon {
+				continue next
+			}
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// mean returns the mean of the points of the polygon.
+func mean(points []float32) (float32, float32) {
+	var x, y float32
+	n := len(points) / 2
+	for i := 0; i < n; i++ {
+		x += points[i*2]
+		y += points[i*2+1]
+	}
+	return x / float32(n), y / float32(n)
+}
@@ -0,0 +1,141 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/shaders"
+)
+
+const (
+	// Maximum distance in pixels between the outline of an
+	// ellipse and its approximating polygon.
+	segmentTolerance = 0.25
+
+	// Bounds for the automatic number of segments
+	minSegments = 8
+	maxSegments = 512
+)
+
+// Ellipse represents an ellipse shape. The ellipse is approximated
+// by a polygon drawn as a triangle fan.
+type Ellipse struct {
+	Base
+
+	// Radii of the ellipse
+	rx, ry float32
+
+	// Number of segments chosen by the client, 0 means automatic
+	segments int
+
+	// Number of segments of the current tessellation
+	tessellated int
+}
+
+// NewEllipse creates a new ellipse. It takes as arguments a linked
+// program and the horizontal and vertical radii. The ellipse can be
+// drawn with the default box shaders.
+func NewEllipse(program shaders.Program, rx, ry float32) *Ellipse {
+	ellipse := new(Ellipse)
+	ellipse.rx, ellipse.ry = rx, ry
+	ellipse.program = program
+
+	// Place the ellipse in the origin. The scale must be set
+	// before the tessellation.
+	ellipse.resetTransform(0, 0)
+
+	ellipse.tessellate()
+
+	// Set the default color
+	ellipse.SetColor(DefaultColor)
+
+	return ellipse
+}
+
+// segmentsForRadius returns the number of segments needed to
+// approximate a circle of radius r pixels within segmentTolerance.
+func segmentsForRadius(r float32) int {
+	if r <= segmentTolerance {
+		return minSegments
+	}
+	n := int(math.Ceil(math.Pi / math.Acos(1-segmentTolerance/float64(r))))
+	if n < minSegments {
+		return minSegments
+	}
+	if n > maxSegments {
+		return maxSegments
+	}
+	return n
+}
+
+// tessellate builds the triangle fan: the center followed by the
+// points on the outline, the last one closing the fan.
+func (e *Ellipse) tessellate() {
+	n := e.Segments()
+	e.tessellated = n
+	e.primitive = TriangleFan
+	e.vertices = make([]float32, 0, (n+2)*2)
+	e.vertices = append(e.vertices, 0, 0)
+	for i := 0; i <= n; i++ {
+		a := 2 * math.Pi * float64(i%n) / float64(n)
+		e.vertices = append(e.vertices,
+			e.rx*float32(math.Cos(a)),
+			e.ry*float32(math.Sin(a)),
+		)
+	}
+}
+
+// Radii returns the horizontal and vertical radii of the ellipse.
+func (e *Ellipse) Radii() (float32, float32) {
+	return e.rx, e.ry
+}
+
+// Segments returns the number of segments approximating the outline
+// of the ellipse.
+func (e *Ellipse) Segments() int {
+	if e.segments > 0 {
+		return e.segments
+	}
+	_, sx, sy := e.worldRotationScale()
+	r := mathf.Max(e.rx*mathf.Abs(sx), e.ry*mathf.Abs(sy))
+	return segmentsForRadius(r * pixelScale(e.attached))
+}
+
+// SetSegments sets the number of segments approximating the outline
+// of the ellipse. If n is less than 3 the number of segments is
+// derived from the on-screen radius, i.e. the radius multiplied by
+// the scale of the ellipse and the pixels per unit of its world.
+func (e *Ellipse) SetSegments(n int) {
+	if n < 3 {
+		n = 0
+	}
+	e.segments = n
+	e.tessellate()
+	e.verticesChanged()
+}
+
+// Draw actually renders the ellipse on the surface.
+func (e *Ellipse) Draw() {
+	// The on-screen radius changes with the scale and the zoom of
+	// the world. The vertex colors would be lost, so the outline
+	// is kept while they're set.
+	if e.segments == 0 && !e.vertexColored && e.tessellated != e.Segments() {
+		e.tessellate()
+		e.verticesChanged()
+	}
+	e.render()
+}
+
+// Clone returns a deep copy of the ellipse.
+func (e *Ellipse) Clone() Shape {
+	return e.clone()
+}
+
+// clone returns a deep copy of the ellipse as an *Ellipse.
+func (e *Ellipse) clone() *Ellipse {
+	c := new(Ellipse)
+	c.rx, c.ry = e.rx, e.ry
+	c.segments, c.tessellated = e.segments, e.tessellated
+	e.cloneInto(&c.Base)
+	return c
+}
@@ -0,0 +1,235 @@
+package shapes
+
+import (
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+var glPrimitives = map[Primitive]gl.Enum{
+	Triangles:     gl.TRIANGLES,
+	TriangleStrip: gl.TRIANGLE_STRIP,
+	TriangleFan:   gl.TRIANGLE_FAN,
+	Lines:         gl.LINES,
+}
+
+// noLocation is the ID of the variables missing from a program.
+const noLocation = ^uint32(0)
+
+// glLocations stores the GLSL variables IDs of a program.
+type glLocations struct {
+	colorId       uint32
+	posId         uint32
+	projMatrixId  uint32
+	modelMatrixId uint32
+	viewMatrixId  uint32
+	texInId       uint32
+	texRatioId    uint32
+	textureId     uint32
+	opacityId     uint32
+	premulId      uint32
+}
+
+// GLRenderer renders shapes on the current OpenGL ES 2 context. All
+// its methods must be called from the thread owning the context.
+//
+// The renderer enables blending for its draws and restores the
+// previous blending state of the context afterwards, see Begin.
+type GLRenderer struct {
+	// locations caches the variables IDs of each program
+	locations map[shaders.Program]*glLocations
+
+	// Nesting of the Begin calls
+	depth int
+
+	// Blending state of the context before the outermost Begin
+	saved glBlendState
+
+	// Blend factors last set, valid if blending is true
+	blending     bool
+	blendFactors [2]gl.Enum
+}
+
+// glBlendState is the blending state of an OpenGL context.
+type glBlendState struct {
+	enabled bool
+
+	// Source and destination factors of the color, then of the
+	// alpha
+	factors [4]int32
+}
+
+// NewGLRenderer returns a renderer drawing on the current OpenGL ES
+// 2 context.
+func NewGLRenderer() *GLRenderer {
+	return &GLRenderer{
+		locations: make(map[shaders.Program]*glLocations),
+	}
+}
+
+// programLocations returns the variables IDs of the given program,
+// querying them the first time the program is seen.
+func (r *GLRenderer) programLocations(program shaders.Program) *glLocations {
+	if loc, ok := r.locations[program]; ok {
+		return loc
+	}
+	loc := &glLocations{
+		posId:         program.GetAttribute("pos"),
+		colorId:       program.GetAttribute("color"),
+		projMatrixId:  program.GetUniform("projection"),
+		modelMatrixId: program.GetUniform("model"),
+		viewMatrixId:  program.GetUniform("view"),
+		texInId:       program.GetAttribute("texIn"),
+		textureId:     program.GetUniform("texture"),
+		texRatioId:    program.GetUniform("texRatio"),
+		opacityId:     program.GetUniform("opacity"),
+		premulId:      program.GetUniform("premultiplied"),
+	}
+	r.locations[program] = loc
+	return loc
+}
+
+// Begin saves the blending state of the context. Until the matching
+// End, which restores it, the renderer sets the blend function only
+// when it changes. Begin and End pairs can be nested, only the
+// outermost pair saves and restores the state.
+//
+// Render calls Begin and End itself, and groups call them around
+// their shapes. Calling them around a whole frame saves the state
+// once. Code changing the blending state between Begin and End calls
+// InvalidateBlend.
+func (r *GLRenderer) Begin() {
+	r.depth++
+	if r.depth > 1 {
+		return
+	}
+	r.saved.enabled = gl.IsEnabled(gl.BLEND)
+	for i, name := range []gl.Enum{gl.BLEND_SRC_RGB, gl.BLEND_DST_RGB, gl.BLEND_SRC_ALPHA, gl.BLEND_DST_ALPHA} {
+		gl.GetIntegerv(name, &r.saved.factors[i])
+	}
+	r.blending = false
+}
+
+// End restores the blending state saved by the matching Begin.
+func (r *GLRenderer) End() {
+	if r.depth == 0 {
+		return
+	}
+	r.depth--
+	if r.depth > 0 || !r.blending {
+		return
+	}
+	f := r.saved.factors
+	gl.BlendFuncSeparate(gl.Enum(f[0]), gl.Enum(f[1]), gl.Enum(f[2]), gl.Enum(f[3]))
+	if !r.saved.enabled {
+		gl.Disable(gl.BLEND)
+	}
+	r.blending = false
+}
+
+// Render draws the command on the current OpenGL context.
+func (r *GLRenderer) Render(cmd *DrawCommand) {
+	if len(cmd.Vertices) == 0 {
+		return
+	}
+	r.Begin()
+	defer r.End()
+
+	cmd.Program.Use()
+	loc := r.programLocations(cmd.Program)
+
+	textured := len(cmd.TexCoords) > 0
+	if b := cmd.Buffers; b != nil {
+		attribBuffer(&b.vertices, b.dirty&vertexBuffer != 0, loc.posId, 2, cmd.Vertices)
+		attribBuffer(&b.colors, b.dirty&colorBuffer != 0, loc.colorId, 4, cmd.Colors)
+		if textured {
+			attribBuffer(&b.texCoords, b.dirty&texCoordBuffer != 0, loc.texInId, 2, cmd.TexCoords)
+			b.dirty = 0
+		} else {
+			b.dirty &= texCoordBuffer
+		}
+		// Don't affect client-side arrays
+		gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	} else {
+		gl.VertexAttribPointer(loc.posId, 2, gl.FLOAT, false, 0, &cmd.Vertices[0])
+		gl.EnableVertexAttribArray(loc.posId)
+
+		gl.VertexAttribPointer(loc.colorId, 4, gl.FLOAT, false, 0, &cmd.Colors[0])
+		gl.EnableVertexAttribArray(loc.colorId)
+
+		if textured {
+			gl.VertexAttribPointer(loc.texInId, 2, gl.FLOAT, false, 0, &cmd.TexCoords[0])
+			gl.EnableVertexAttribArray(loc.texInId)
+		}
+	}
+	if !textured && loc.texInId != noLocation {
+		// The coordinates of a previous shape would be read past
+		// their end
+		gl.DisableVertexAttribArray(loc.texInId)
+	}
+
+	gl.UniformMatrix4fv(int32(loc.modelMatrixId), 1, false, (*float32)(&cmd.Model[0]))
+	gl.UniformMatrix4fv(int32(loc.projMatrixId), 1, false, (*float32)(&cmd.Projection[0]))
+	gl.UniformMatrix4fv(int32(loc.viewMatrixId), 1, false, (*float32)(&cmd.View[0]))
+
+	gl.Uniform1f(int32(loc.texRatioId), 0.0)
+
+	// Texture
+	if textured {
+		gl.Uniform1f(int32(loc.texRatioId), 1.0)
+		gl.ActiveTexture(gl.TEXTURE0)
+		var id uint32
+		if cmd.Texture != nil {
+			cmd.Texture.Upload()
+			id = cmd.Texture.id
+		}
+		gl.BindTexture(gl.TEXTURE_2D, id)
+		gl.Uniform1i(int32(loc.textureId), 0)
+	}
+
+	// Blending, the shaders premultiply the texture colors unless
+	// they already are
+	premultiplied := cmd.Blend == PremultipliedBlend ||
+		textured && cmd.Texture != nil && cmd.Texture.options.PremultipliedAlpha
+	gl.Uniform1f(int32(loc.opacityId), cmd.Opacity)
+	if premultiplied {
+		gl.Uniform1f(int32(loc.premulId), 1.0)
+	} else {
+		gl.Uniform1f(int32(loc.premulId), 0.0)
+	}
+	r.setBlend(glBlendFactors[cmd.Blend])
+
+	gl.DrawArrays(glPrimitives[cmd.Primitive], 0, gl.Sizei(len(cmd.Vertices)/2))
+}
+
+// InvalidateBlend makes the renderer set the blending state again at
+// the next draw. It's needed only between Begin and End.
+func (r *GLRenderer) InvalidateBlend() {
+	r.blending = false
+}
+
+// setBlend enables blending with the given factors, unless they are
+// already set.
+func (r *GLRenderer) setBlend(factors [2]gl.Enum) {
+	if !r.blending {
+		gl.Enable(gl.BLEND)
+	} else if factors == r.blendFactors {
+		return
+	}
+	gl.BlendFunc(factors[0], factors[1])
+	r.blending, r.blendFactors = true, factors
+}
+
+// attribBuffer binds the buffer to the attribute, creating the buffer
+// if needed and uploading data if the buffer is new or dirty.
+func attribBuffer(id *uint32, dirty bool, attrib uint32, size int32, data []float32) {
+	if *id == 0 {
+		gl.GenBuffers(1, id)
+		dirty = true
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, *id)
+	if dirty {
+		gl.BufferData(gl.ARRAY_BUFFER, gl.Sizeiptr(len(data)*4), gl.Void(&data[0]), gl.STATIC_DRAW)
+	}
+	gl.VertexAttribPointer(attrib, size, gl.FLOAT, false, 0, nil)
+	gl.EnableVertexAttribArray(attrib)
+}
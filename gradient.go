@@ -0,0 +1,418 @@
+package shapes
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/aded/shapes/internal/mathf"
+)
+
+// ColorStop is a color placed at the given offset of a gradient.
+// Offsets range from 0, the start of the gradient, to 1, its end.
+type ColorStop struct {
+	Offset float32
+	Color  color.Color
+}
+
+// Gradient is a fill whose color changes across the shape. Shapes
+// filled with a gradient are subdivided so that the colors of their
+// vertices, interpolated across the triangles, follow the gradient.
+type Gradient interface {
+	// At returns the color of the gradient in (x, y), in shape
+	// coordinates.
+	At(x, y float32) color.Color
+
+	// offset returns the unclamped offset of (x, y) along the
+	// gradient.
+	offset(x, y float32) float32
+
+	// stops returns the color stops of the gradient.
+	stops() []ColorStop
+
+	// subdivide splits the triangles, and the interpolated
+	// attributes, where the colors of the vertices don't
+	// approximate the gradient.
+	subdivide(m *mesh)
+}
+
+// LinearGradient changes color along the line from (X0, Y0) to (X1,
+// Y1), in shape coordinates. The color is constant along the lines
+// perpendicular to it and clamped before the start and after the
+// end.
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float32
+
+	// Stops in increasing offset order
+	Stops []ColorStop
+}
+
+// At returns the color of the gradient in (x, y).
+func (g LinearGradient) At(x, y float32) color.Color {
+	return colorOf(gradientColor(g, x, y))
+}
+
+func (g LinearGradient) offset(x, y float32) float32 {
+	dx, dy := g.X1-g.X0, g.Y1-g.Y0
+	l := dx*dx + dy*dy
+	if l == 0 {
+		return 0
+	}
+	return ((x-g.X0)*dx + (y-g.Y0)*dy) / l
+}
+
+func (g LinearGradient) stops() []ColorStop {
+	return g.Stops
+}
+
+// subdivide splits the triangles along the lines of the stops, and
+// of the ends of the gradient, where the color changes slope. The
+// colors are then exact.
+func (g LinearGradient) subdivide(m *mesh) {
+	m.split(g.offset, 0)
+	for _, s := range g.Stops {
+		if s.Offset > 0 && s.Offset < 1 {
+			m.split(g.offset, s.Offset)
+		}
+	}
+	m.split(g.offset, 1)
+}
+
+// RadialGradient changes color from the center (X, Y), in shape
+// coordinates, to the circle of the given radius. The color is
+// clamped outside the circle.
+type RadialGradient struct {
+	X, Y, Radius float32
+
+	// Stops in increasing offset order
+	Stops []ColorStop
+}
+
+// At returns the color of the gradient in (x, y).
+func (g RadialGradient) At(x, y float32) color.Color {
+	return colorOf(gradientColor(g, x, y))
+}
+
+func (g RadialGradient) offset(x, y float32) float32 {
+	if g.Radius <= 0 {
+		return 1
+	}
+	d := math.Hypot(float64(x-g.X), float64(y-g.Y))
+	return float32(d) / g.Radius
+}
+
+func (g RadialGradient) stops() []ColorStop {
+	return g.Stops
+}
+
+// radialSteps is the number of subdivisions of the radius of a radial
+// gradient below which the edges are not split anymore.
+const radialSteps = 32
+
+// maxGradientTriangles limits the triangles of a shape subdivided for
+// a radial gradient.
+const maxGradientTriangles = 2048
+
+// gradientTolerance is the largest difference of a color component,
+// in the [0, 1] range, between the gradient and its interpolation
+// along an edge.
+const gradientTolerance = 4.0 / 255
+
+// subdivide first splits the triangles along the axes through the
+// center, making it a vertex, then splits the edges whose
+// interpolated colors don't follow the gradient: the edges crossing
+// the circles of the stops and the long edges where the offset
+// curves.
+func (g RadialGradient) subdivide(m *mesh) {
+	if g.Radius <= 0 {
+		return
+	}
+	m.split(func(x, y float32) float32 { return x }, g.X)
+	m.split(func(x, y float32) float32 { return y }, g.Y)
+	minEdge := g.Radius / radialSteps
+	m.refine(func(a, b meshVertex) bool {
+		if length(a, b) <= minEdge {
+			return false
+		}
+		ca, cb := gradientColor(g, a.x, a.y), gradientColor(g, b.x, b.y)
+		mid := gradientColor(g, (a.x+b.x)/2, (a.y+b.y)/2)
+		for j := range mid {
+			if mathf.Abs(mid[j]-(ca[j]+cb[j])/2) > gradientTolerance {
+				return true
+			}
+		}
+		return false
+	}, maxGradientTriangles)
+}
+
+// gradientColor returns the normalized color of the gradient in (x,
+// y), interpolating the stops around the offset of the point.
+func gradientColor(g Gradient, x, y float32) [4]float32 {
+	stops := g.stops()
+	if len(stops) == 0 {
+		return [4]float32{}
+	}
+	t := mathf.Clamp(g.offset(x, y), 0, 1)
+	if t <= stops[0].Offset {
+		return normalize(stops[0].Color)
+	}
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].Offset {
+			continue
+		}
+		a, b := stops[i-1], stops[i]
+		var k float32
+		if b.Offset > a.Offset {
+			k = (t - a.Offset) / (b.Offset - a.Offset)
+		}
+		ca, cb := normalize(a.Color), normalize(b.Color)
+		var c [4]float32
+		for j := range c {
+			c[j] = ca[j] + (cb[j]-ca[j])*k
+		}
+		return c
+	}
+	return normalize(stops[len(stops)-1].Color)
+}
+
+// normalize returns the non-premultiplied components of the color in
+// the [0, 1] range.
+func normalize(c color.Color) [4]float32 {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return [4]float32{
+		float32(rgba.R) / 255,
+		float32(rgba.G) / 255,
+		float32(rgba.B) / 255,
+		float32(rgba.A) / 255,
+	}
+}
+
+// colorOf converts normalized components back to a color.
+func colorOf(c [4]float32) color.Color {
+	return color.NRGBA{
+		uint8(c[0]*255 + 0.5),
+		uint8(c[1]*255 + 0.5),
+		uint8(c[2]*255 + 0.5),
+		uint8(c[3]*255 + 0.5),
+	}
+}
+
+// mesh is a list of triangles with optional texture coordinates,
+// interpolated when the triangles are split.
+type mesh struct {
+	vertices  []float32
+	texCoords []float32
+}
+
+// newMesh returns the triangles assembled from the vertices with the
+// given primitive.
+func newMesh(p Primitive, vertices, texCoords []float32) *mesh {
+	m := new(mesh)
+	textured := len(texCoords) == len(vertices)
+	eachTriangle(p, len(vertices)/2, func(i0, i1, i2 int) {
+		for _, i := range []int{i0, i1, i2} {
+			m.vertices = append(m.vertices, vertices[i*2], vertices[i*2+1])
+			if textured {
+				m.texCoords = append(m.texCoords, texCoords[i*2], texCoords[i*2+1])
+			}
+		}
+	})
+	return m
+}
+
+// meshVertex is a vertex with its texture coordinates.
+type meshVertex struct {
+	x, y, s, t float32
+}
+
+func (m *mesh) vertex(i int) meshVertex {
+	v := meshVertex{x: m.vertices[i*2], y: m.vertices[i*2+1]}
+	if len(m.texCoords) > 0 {
+		v.s, v.t = m.texCoords[i*2], m.texCoords[i*2+1]
+	}
+	return v
+}
+
+func (m *mesh) triangle(out *mesh, a, b, c meshVertex) {
+	out.vertices = append(out.vertices, a.x, a.y, b.x, b.y, c.x, c.y)
+	if len(m.texCoords) > 0 {
+		out.texCoords = append(out.texCoords, a.s, a.t, b.s, b.t, c.s, c.t)
+	}
+}
+
+// lerp returns the vertex at k along the edge from a to b.
+func lerp(a, b meshVertex, k float32) meshVertex {
+	return meshVertex{
+		a.x + (b.x-a.x)*k,
+		a.y + (b.y-a.y)*k,
+		a.s + (b.s-a.s)*k,
+		a.t + (b.t-a.t)*k,
+	}
+}
+
+// midpoint returns the vertex halfway between a and b. It doesn't
+// depend on the order of a and b, so triangles sharing an edge get
+// the same vertex.
+func midpoint(a, b meshVertex) meshVertex {
+	return meshVertex{(a.x + b.x) / 2, (a.y + b.y) / 2, (a.s + b.s) / 2, (a.t + b.t) / 2}
+}
+
+// split cuts the triangles crossed by the line where f equals level.
+// f must be linear. Triangles touching the line only at a vertex or
+// along an edge are kept whole.
+func (m *mesh) split(f func(x, y float32) float32, level float32) {
+	out := new(mesh)
+	for i := 0; i+2 < len(m.vertices)/2; i += 3 {
+		v := [3]meshVertex{m.vertex(i), m.vertex(i + 1), m.vertex(i + 2)}
+		var d [3]float32
+		above, below := 0, 0
+		for j := range v {
+			d[j] = f(v[j].x, v[j].y) - level
+			if d[j] > 0 {
+				above++
+			} else if d[j] < 0 {
+				below++
+			}
+		}
+		if above == 0 || below == 0 {
+			m.triangle(out, v[0], v[1], v[2])
+			continue
+		}
+		// Find the vertex alone on its side, keeping the winding
+		lone := 0
+		for j := range v {
+			if (above == 1 && d[j] > 0) || (above != 1 && d[j] < 0) {
+				lone = j
+			}
+		}
+		a, b, c := v[lone], v[(lone+1)%3], v[(lone+2)%3]
+		da, db, dc := d[lone], d[(lone+1)%3], d[(lone+2)%3]
+		switch {
+		case db == 0:
+			// The line goes through b
+			ac := lerp(a, c, da/(da-dc))
+			m.triangle(out, a, b, ac)
+			m.triangle(out, b, c, ac)
+		case dc == 0:
+			// The line goes through c
+			ab := lerp(a, b, da/(da-db))
+			m.triangle(out, a, ab, c)
+			m.triangle(out, ab, b, c)
+		default:
+			ab := lerp(a, b, da/(da-db))
+			ac := lerp(a, c, da/(da-dc))
+			m.triangle(out, a, ab, ac)
+			m.triangle(out, ab, b, c)
+			m.triangle(out, ab, c, ac)
+		}
+	}
+	*m = *out
+}
+
+// meshEdge identifies the edge between two vertices regardless of their
+// order.
+type meshEdge [4]float32
+
+func meshEdgeOf(a, b meshVertex) meshEdge {
+	if b.x < a.x || (b.x == a.x && b.y < a.y) {
+		a, b = b, a
+	}
+	return meshEdge{a.x, a.y, b.x, b.y}
+}
+
+// refine splits in half the edges for which needsSplit returns true,
+// until no edge needs it or the triangles would be more than
+// maxTriangles. A triangle with split edges has its longest edge
+// split too, which keeps the triangles from getting thinner.
+// needsSplit must not depend on the order of the ends of the edge.
+func (m *mesh) refine(needsSplit func(a, b meshVertex) bool, maxTriangles int) {
+	for {
+		count := len(m.vertices) / 6
+		triangles := make([][3]meshVertex, count)
+		split := make(map[meshEdge]bool)
+		for i := range triangles {
+			v := [3]meshVertex{m.vertex(i * 3), m.vertex(i*3 + 1), m.vertex(i*3 + 2)}
+			triangles[i] = v
+			for j := range v {
+				if needsSplit(v[j], v[(j+1)%3]) {
+					split[meshEdgeOf(v[j], v[(j+1)%3])] = true
+				}
+			}
+		}
+		if len(split) == 0 {
+			return
+		}
+
+		// Mark the longest edges of the triangles with split edges
+		// until no triangle is left without
+		for marked := true; marked; {
+			marked = false
+			for _, v := range triangles {
+				l := longestEdge(v)
+				if split[meshEdgeOf(v[l], v[(l+1)%3])] {
+					continue
+				}
+				for j := range v {
+					if split[meshEdgeOf(v[j], v[(j+1)%3])] {
+						split[meshEdgeOf(v[l], v[(l+1)%3])] = true
+						marked = true
+						break
+					}
+				}
+			}
+		}
+		for _, v := range triangles {
+			for j := range v {
+				if split[meshEdgeOf(v[j], v[(j+1)%3])] {
+					count++
+				}
+			}
+		}
+		if count > maxTriangles {
+			return
+		}
+
+		out := new(mesh)
+		for _, v := range triangles {
+			// Rotate the longest edge to a-b
+			l := longestEdge(v)
+			a, b, c := v[l], v[(l+1)%3], v[(l+2)%3]
+			if !split[meshEdgeOf(a, b)] {
+				m.triangle(out, a, b, c)
+				continue
+			}
+			ab := midpoint(a, b)
+			if split[meshEdgeOf(b, c)] {
+				bc := midpoint(b, c)
+				m.triangle(out, ab, b, bc)
+				m.triangle(out, ab, bc, c)
+			} else {
+				m.triangle(out, ab, b, c)
+			}
+			if split[meshEdgeOf(c, a)] {
+				ca := midpoint(c, a)
+				m.triangle(out, a, ab, ca)
+				m.triangle(out, ca, ab, c)
+			} else {
+				m.triangle(out, a, ab, c)
+			}
+		}
+		*m = *out
+	}
+}
+
+// longestEdge returns the index of the first vertex of the longest
+// edge of the triangle.
+func longestEdge(v [3]meshVertex) int {
+	l := 0
+	for j := 1; j < 3; j++ {
+		if length(v[j], v[(j+1)%3]) > length(v[l], v[(l+1)%3]) {
+			l = j
+		}
+	}
+	return l
+}
+
+// length returns the length of the edge from a to b.
+func length(a, b meshVertex) float32 {
+	return float32(math.Hypot(float64(b.x-a.x), float64(b.y-a.y)))
+}
@@ -1,139 +1,711 @@
 package shapes
 
 import (
+	"fmt"
 	"image"
+	"math"
+	"sort"
 	"sync"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
 )
 
 // Group is a structure for grouping shapes. It implements Shape.
+//
+// Groups are the nodes of a scene graph: the transform of each shape
+// is relative to the group containing it, so moving, rotating or
+// scaling a group affects all its shapes without changing their
+// transforms.
 type Group struct {
-	// Center of the group
-	x, y float32
-
-	// Angle
-	angle float32
-
-	// Bounds of the group
-	bounds image.Rectangle
+	// Local transform and position in the scene graph
+	node
 
 	// rwMutex handle councurrent access to children slice
 	rwMutex sync.RWMutex
 
 	// children is the slice containing the shapes of the group
 	children []Shape
+
+	// renderer is given to the shapes appended to the group
+	renderer Renderer
+
+	// culling skips the shapes out of the visible area of the
+	// world when drawing
+	culling bool
+
+	// Counters of the last Draw
+	statsMutex sync.Mutex
+	stats      DrawStats
+
+	// Optional grid of the shapes, for the spatial queries
+	index spatialIndex
+}
+
+// DrawStats counts the shapes drawn and culled by Group.Draw.
+type DrawStats struct {
+	// Drawn is the number of shapes drawn, including the ones
+	// in nested groups.
+	Drawn int
+
+	// Culled is the number of shapes and nested groups skipped
+	// because they were out of the visible area.
+	Culled int
 }
 
 // NewGroup instantiates a group object.
 func NewGroup() *Group {
 	return &Group{
+		node:     node{transform: NewTransform()},
 		children: make([]Shape, 0),
 	}
 }
 
-// Append appends a shape to the group.
-func (g *Group) Append(s Shape) {
+// Append appends a shape to the group. An error is returned if the
+// shape is the group itself or one of its ancestors.
+func (g *Group) Append(s Shape) error {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
 
+	if g.isAncestor(s) {
+		return fmt.Errorf("cannot add a group to itself or to one of its descendants")
+	}
+	g.adopt(s)
 	g.children = append(g.children, s)
+	g.index.renumber(g.children, len(g.children)-1)
+	return nil
+}
+
+// InsertAt inserts a shape in the group at position i. Shapes from
+// position i on are shifted by one. An error is returned if i is
+// out of the [0, Len()] range or if the shape is the group itself or
+// one of its ancestors.
+func (g *Group) InsertAt(i int, s Shape) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
 
-	if len(g.children) == 1 {
-		g.bounds = s.Bounds()
-	} else {
-		g.bounds = g.bounds.Union(s.Bounds())
+	if i < 0 || i > len(g.children) {
+		return fmt.Errorf("cannot insert a shape at position %d in a group of %d shapes", i, len(g.children))
+	}
+	if g.isAncestor(s) {
+		return fmt.Errorf("cannot add a group to itself or to one of its descendants")
 	}
 
-	g.x = float32((g.bounds.Min.X + g.bounds.Max.X) / 2)
-	g.y = float32((g.bounds.Min.Y + g.bounds.Max.Y) / 2)
+	g.adopt(s)
+	g.children = append(g.children, nil)
+	copy(g.children[i+1:], g.children[i:])
+	g.children[i] = s
+	g.index.renumber(g.children, i)
+	return nil
 }
 
-// func (g *Group) Remove(k string) error {
-// 	g.rwMutex.Lock()
-// 	defer g.rwMutex.Unlock()
+// Remove removes the shape from the group. An error is returned if
+// the shape doesn't belong to the group.
+func (g *Group) Remove(s Shape) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
 
-// 	if _, exists := g.children[k]; !exists {
-// 		return fmt.Errorf("cannot find a shape named '%s'", k)
-// 	}
+	i := g.indexOf(s)
+	if i < 0 {
+		return fmt.Errorf("cannot find the shape in the group")
+	}
+	g.removeAt(i)
+	return nil
+}
 
-// 	g.children[k] = nil
+// RemoveAt removes the shape at position i from the group. An error
+// is returned if i is out of range.
+func (g *Group) RemoveAt(i int) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
 
-// 	// TODO recalculate center and bounds
+	if i < 0 || i >= len(g.children) {
+		return fmt.Errorf("cannot remove the shape at position %d from a group of %d shapes", i, len(g.children))
+	}
+	g.removeAt(i)
+	return nil
+}
 
-// 	return nil
-// }
+// removeAt removes the shape at position i. The caller must hold the
+// lock.
+func (g *Group) removeAt(i int) {
+	orphan(g.children[i])
+	g.index.remove(g.children[i])
+	copy(g.children[i:], g.children[i+1:])
+	g.children[len(g.children)-1] = nil
+	g.children = g.children[:len(g.children)-1]
+	g.index.renumber(g.children, i)
+	g.boundsChanged()
+}
 
-// GetAt returns the shape at position id in the group.
-func (g *Group) GetAt(id int) Shape {
-	return g.children[id]
+// Clear removes all the shapes from the group.
+func (g *Group) Clear() {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
+
+	for _, s := range g.children {
+		orphan(s)
+	}
+	g.children = make([]Shape, 0)
+	g.index.reset(g.index.size())
+	g.boundsChanged()
 }
 
-// Draw draws all the shapes in the group calling their Draw method.
-func (g *Group) Draw() {
+// Len returns the number of shapes in the group.
+func (g *Group) Len() int {
 	g.rwMutex.RLock()
 	defer g.rwMutex.RUnlock()
-	for _, s := range g.children {
-		s.Draw()
+	return len(g.children)
+}
+
+// Each calls fn for each shape of the group, in drawing order, until
+// fn returns false. fn must not modify the group.
+func (g *Group) Each(fn func(Shape) bool) {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	for _, s := range g.drawOrder() {
+		if !fn(s) {
+			return
+		}
 	}
 }
 
-// func (g *Group) Rotate(angle float32, p ...mathgl.Vec2f) {
-// 	var pivot mathgl.Vec2f
+// BringToFront moves the shape in front of the other shapes in its
+// layer. An error is returned if the shape doesn't belong to the
+// group.
+func (g *Group) BringToFront(s Shape) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
+
+	i := g.indexOf(s)
+	if i < 0 {
+		return fmt.Errorf("cannot find the shape in the group")
+	}
+	copy(g.children[i:], g.children[i+1:])
+	g.children[len(g.children)-1] = s
+	g.index.renumber(g.children, i)
+	return nil
+}
 
-// 	if len(p) > 0 {
-// 		pivot = p[0]
-// 	} else {
-// 		cx, cy := g.Center()
-// 		pivot = mathgl.Vec2f{cx, cy}
-// 	}
+// SendToBack moves the shape behind the other shapes in its layer. An
+// error is returned if the shape doesn't belong to the group.
+func (g *Group) SendToBack(s Shape) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
 
-// 	for _, s := range g.children {
-// 		s.Rotate(angle, pivot)
+	i := g.indexOf(s)
+	if i < 0 {
+		return fmt.Errorf("cannot find the shape in the group")
+	}
+	copy(g.children[1:], g.children[:i])
+	g.children[0] = s
+	g.index.renumber(g.children[:i+1], 0)
+	return nil
+}
 
-// RotateAround rotates the group around the given point, by the given
-// angle in degrees.
-func (g *Group) RotateAround(x, y, angle float32) {
+// Raise swaps the shape with the one drawn right after it in its
+// layer. An error is returned if the shape doesn't belong to the
+// group.
+func (g *Group) Raise(s Shape) error {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
-	for _, s := range g.children {
-		s.RotateAround(x, y, angle)
+
+	i := g.indexOf(s)
+	if i < 0 {
+		return fmt.Errorf("cannot find the shape in the group")
+	}
+	for j := i + 1; j < len(g.children); j++ {
+		if g.children[j].ZIndex() == s.ZIndex() {
+			g.children[i], g.children[j] = g.children[j], g.children[i]
+			g.index.renumber(g.children[:j+1], i)
+			break
+		}
 	}
+	return nil
 }
 
-// Rotate rotates the group aroung its center.
-func (g *Group) Rotate(angle float32) {
+// Lower swaps the shape with the one drawn right before it in its
+// layer. An error is returned if the shape doesn't belong to the
+// group.
+func (g *Group) Lower(s Shape) error {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
-	for _, s := range g.children {
-		s.RotateAround(g.x, g.y, angle)
+
+	i := g.indexOf(s)
+	if i < 0 {
+		return fmt.Errorf("cannot find the shape in the group")
+	}
+	for j := i - 1; j >= 0; j-- {
+		if g.children[j].ZIndex() == s.ZIndex() {
+			g.children[i], g.children[j] = g.children[j], g.children[i]
+			g.index.renumber(g.children[:i+1], j)
+			break
+		}
 	}
+	return nil
 }
 
-// Scale scales each shape of the group.
-func (g *Group) Scale(sx, sy float32) {
-	for _, s := range g.children {
-		s.Scale(sx, sy)
+// indexOf returns the position of the shape in the group, -1 if the
+// shape doesn't belong to the group. The caller must hold the lock.
+func (g *Group) indexOf(s Shape) int {
+	for i, child := range g.children {
+		if child == s {
+			return i
+		}
 	}
+	return -1
 }
 
-// Move moves each shape of the group by dx, dy.
-func (g *Group) Move(dx, dy float32) {
+// drawOrder returns the shapes sorted by z-index, shapes with the
+// same z-index in insertion order. The caller must hold the lock.
+func (g *Group) drawOrder() []Shape {
+	less := func(children []Shape) func(i, j int) bool {
+		return func(i, j int) bool {
+			return children[i].ZIndex() < children[j].ZIndex()
+		}
+	}
+	if sort.SliceIsSorted(g.children, less(g.children)) {
+		return g.children
+	}
+	ordered := make([]Shape, len(g.children))
+	copy(ordered, g.children)
+	sort.SliceStable(ordered, less(ordered))
+	return ordered
+}
+
+// adopt makes the group the parent of the shape, removing it from
+// its previous group, and gives it the renderer and the world of the
+// group. The caller must hold the lock.
+// isAncestor returns true if the shape is the group or one of its
+// ancestors. Adding it to the group would make a cycle.
+func (g *Group) isAncestor(s Shape) bool {
+	c, ok := s.(child)
+	if !ok {
+		return false
+	}
+	for p := g; p != nil; p = p.parent {
+		if &p.node == c.graphNode() {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) adopt(s Shape) {
+	if g.renderer != nil {
+		s.SetRenderer(g.renderer)
+	}
+	if g.attached != nil {
+		s.AttachToWorld(g.attached)
+	}
+	if p := s.Parent(); p != nil && p != g {
+		p.Remove(s)
+	}
+	if c, ok := s.(child); ok {
+		c.setParent(g)
+		c.invalidateWorld()
+	}
+	g.index.insert(s)
+	g.boundsChanged()
+}
+
+// childChanged is called when the bounds of a child have changed. The
+// bounds of the group change too.
+func (g *Group) childChanged(n *node) {
+	g.index.invalidate(n)
+	g.boundsChanged()
+}
+
+// orphan detaches the shape from its group.
+func orphan(s Shape) {
+	if c, ok := s.(child); ok {
+		c.setParent(nil)
+		c.invalidateWorld()
+	}
+}
+
+// GetAt returns the shape at position id in the group.
+func (g *Group) GetAt(id int) Shape {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	return g.children[id]
+}
+
+// Contains returns true if the point (x, y) lies inside one of the
+// shapes of the group.
+func (g *Group) Contains(x, y float32) bool {
+	return g.ShapeAt(x, y) != nil
+}
+
+// ShapeAt returns the topmost shape of the group containing the point
+// (x, y), i.e. the last drawn. It returns nil if no shape contains
+// the point.
+func (g *Group) ShapeAt(x, y float32) Shape {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	if g.index.size() > 0 {
+		local, ok := g.toLocalRect(Rect{x, y, x, y})
+		if !ok {
+			return nil
+		}
+		var top *indexEntry
+		for _, e := range g.index.entriesIn(local) {
+			if e.shape.Contains(x, y) && (top == nil || drawnBefore(top, e)) {
+				top = e
+			}
+		}
+		if top == nil {
+			return nil
+		}
+		return top.shape
+	}
+	ordered := g.drawOrder()
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if ordered[i].Contains(x, y) {
+			return ordered[i]
+		}
+	}
+	return nil
+}
+
+// SetSpatialIndex indexes the shapes of the group in a grid of square
+// cells of the given size, in the coordinates of the group, so that
+// ShapeAt, ShapesAt, ShapesIn and Nearest don't test every shape. The
+// index is maintained as the shapes change. Choose a size close to
+// the size of the typical shape. A size not greater than 0 removes
+// the index.
+func (g *Group) SetSpatialIndex(cellSize float32) {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
+	g.index.reset(cellSize)
 	for _, s := range g.children {
-		s.Move(dx, dy)
+		g.index.insert(s)
+	}
+	g.index.renumber(g.children, 0)
+}
+
+// SpatialIndex returns the size of the cells of the spatial index of
+// the group, 0 if the group is not indexed.
+func (g *Group) SpatialIndex() float32 {
+	return g.index.size()
+}
+
+// ShapesIn returns the shapes of the group whose bounding rectangles
+// overlap r, in world coordinates, in no particular order.
+func (g *Group) ShapesIn(r Rect) []Shape {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	candidates := g.children
+	if g.index.size() > 0 {
+		local, ok := g.toLocalRect(r)
+		if !ok {
+			return nil
+		}
+		candidates = g.index.candidates(local)
+	}
+	var result []Shape
+	for _, s := range candidates {
+		if s.AABB().Overlaps(r) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// ShapesAt returns the shapes of the group containing the point (x,
+// y), in world coordinates, in no particular order.
+func (g *Group) ShapesAt(x, y float32) []Shape {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	return g.shapesAt(x, y)
+}
+
+// shapesAt returns the shapes containing the point (x, y). The caller
+// must hold the lock.
+func (g *Group) shapesAt(x, y float32) []Shape {
+	candidates := g.children
+	if g.index.size() > 0 {
+		local, ok := g.toLocalRect(Rect{x, y, x, y})
+		if !ok {
+			return nil
+		}
+		candidates = g.index.candidates(local)
+	}
+	var result []Shape
+	for _, s := range candidates {
+		if s.Contains(x, y) {
+			result = append(result, s)
+		}
 	}
-	g.x, g.y = g.x+dx, g.y+dy
+	return result
 }
 
-// MoveTo moves the group in the (x,y) position.
+// Nearest returns the shape of the group whose bounding rectangle, in
+// the coordinates of the group, is the nearest to the point (x, y) in
+// world coordinates. It returns nil if the group is empty.
+func (g *Group) Nearest(x, y float32) Shape {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	local, ok := g.toLocalRect(Rect{x, y, x, y})
+	if !ok {
+		return nil
+	}
+	lx, ly := local.MinX, local.MinY
+
+	var best Shape
+	bestDist := float32(math.Inf(1))
+	candidates := g.children
+	if g.index.size() > 0 {
+		best, bestDist = g.index.nearest(lx, ly)
+		candidates = g.index.looseShapes()
+	}
+	for _, s := range candidates {
+		if d := rectDistance(g.localBounds(s), lx, ly); d < bestDist {
+			best, bestDist = s, d
+		}
+	}
+	return best
+}
+
+// toLocalRect returns the smallest rectangle, in the coordinates of
+// the group, containing r in world coordinates. It returns false if
+// the group is scaled to nothing.
+func (g *Group) toLocalRect(r Rect) (Rect, bool) {
+	m := g.world()
+	if m.Det() == 0 {
+		return Rect{}, false
+	}
+	if r.Empty() {
+		return r, true
+	}
+	return rectOf(transformPoints(m.Inv(), []float32{
+		r.MinX, r.MinY, r.MaxX, r.MinY, r.MaxX, r.MaxY, r.MinX, r.MaxY,
+	})), true
+}
+
+// localBounds returns the bounds of the shape in the coordinates of
+// the group.
+func (g *Group) localBounds(s Shape) Rect {
+	if c, ok := s.(child); ok {
+		return c.boundsIn(mathgl.Ident4f())
+	}
+	local, _ := g.toLocalRect(s.AABB())
+	return local
+}
+
+// Release deletes the OpenGL resources allocated for the shapes in
+// the group.
+func (g *Group) Release() {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	for _, child := range g.children {
+		child.Release()
+	}
+}
+
+// Hulls returns the convex polygons of all the shapes in the group.
+func (g *Group) Hulls() [][]float32 {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	var hulls [][]float32
+	for _, child := range g.children {
+		hulls = append(hulls, child.Hulls()...)
+	}
+	return hulls
+}
+
+// Draw draws all the shapes in the group calling their Draw
+// method, sorted by z-index. The shapes are transformed by the world
+// matrix of the group, computed once and cached until the group
+// changes. With culling enabled, the shapes whose bounds are out of
+// the visible area of the world are skipped. Renderers buffering the
+// commands, like BatchRenderer, are flushed at the end, and renderers
+// changing the state of the context restore it.
+func (g *Group) Draw() {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+
+	renderer := g.renderer
+	if renderer == nil {
+		renderer = DefaultRenderer
+	}
+	if r, ok := renderer.(StateRestorer); ok {
+		r.Begin()
+		defer r.End()
+	}
+
+	var stats DrawStats
+	visible, cull := Rect{}, false
+	if g.attached != nil && g.cullingEnabled() {
+		visible, cull = VisibleRect(g.attached)
+	}
+	ordered := g.drawOrder()
+	if cull && g.index.size() > 0 {
+		// Only the shapes indexed near the visible area are tested
+		ordered = nil
+		if local, ok := g.toLocalRect(visible); ok {
+			entries := g.index.entriesIn(local)
+			sort.Slice(entries, func(i, j int) bool {
+				return drawnBefore(entries[i], entries[j])
+			})
+			for _, e := range entries {
+				ordered = append(ordered, e.shape)
+			}
+		}
+		stats.Culled = len(g.children) - len(ordered)
+	}
+	for _, s := range ordered {
+		if cull && !s.AABB().Overlaps(visible) {
+			stats.Culled++
+			continue
+		}
+		s.Draw()
+		if group, ok := s.(*Group); ok {
+			nested := group.Stats()
+			stats.Drawn += nested.Drawn
+			stats.Culled += nested.Culled
+		} else {
+			stats.Drawn++
+		}
+	}
+	if f, ok := renderer.(Flusher); ok {
+		f.Flush()
+	}
+
+	g.statsMutex.Lock()
+	g.stats = stats
+	g.statsMutex.Unlock()
+}
+
+// Culling returns true if the group skips the shapes out of the
+// visible area when drawing.
+func (g *Group) Culling() bool {
+	return g.culling
+}
+
+// SetCulling enables or disables the culling of the shapes out of
+// the visible area of the world the group is attached to. Culling
+// applies to the nested groups too.
+func (g *Group) SetCulling(enabled bool) {
+	g.culling = enabled
+}
+
+// cullingEnabled returns true if culling is enabled on the group or
+// on one of its ancestors.
+func (g *Group) cullingEnabled() bool {
+	for p := g; p != nil; p = p.parent {
+		if p.culling {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the number of shapes drawn and culled by the last
+// Draw.
+func (g *Group) Stats() DrawStats {
+	g.statsMutex.Lock()
+	defer g.statsMutex.Unlock()
+	return g.stats
+}
+
+// RotateAround rotates the group around the given point, in the
+// coordinates of the parent, by the given angle in degrees.
+func (g *Group) RotateAround(x, y, angle float32) {
+	t := g.transform
+	dx, dy := rotate(t.X-x, t.Y-y, angle)
+	t.X, t.Y = x+dx, y+dy
+	t.Angle += angle
+	g.SetTransform(t)
+}
+
+// Rotate rotates the group around its center.
+func (g *Group) Rotate(angle float32) {
+	g.pivotToCenter()
+	t := g.transform
+	t.Angle += angle
+	g.SetTransform(t)
+}
+
+// Scale scales the group around its center. Factors multiply the
+// current scale.
+func (g *Group) Scale(sx, sy float32) {
+	g.pivotToCenter()
+	t := g.transform
+	t.ScaleX *= sx
+	t.ScaleY *= sy
+	g.SetTransform(t)
+}
+
+// Move moves the group by dx, dy in the coordinates of the parent.
+func (g *Group) Move(dx, dy float32) {
+	t := g.transform
+	t.X += dx
+	t.Y += dy
+	g.SetTransform(t)
+}
+
+// MoveTo moves the center of the group in the (x,y) position.
 func (g *Group) MoveTo(x, y float32) {
-	g.rwMutex.Lock()
-	defer g.rwMutex.Unlock()
-	dx, dy := x-g.x, y-g.y
+	cx, cy := g.Center()
+	g.Move(x-cx, y-cy)
+}
+
+// SetTransform sets the position, rotation, scale and pivot of the
+// group relative to its parent.
+func (g *Group) SetTransform(t Transform) {
+	g.setTransform(t)
+	g.invalidateWorld()
+}
+
+// SetPivot sets the pivot of the group, in group coordinates, without
+// moving the group.
+func (g *Group) SetPivot(x, y float32) {
+	t := g.transform
+	px, py := t.Apply(x, y)
+	t.X, t.Y = px, py
+	t.PivotX, t.PivotY = x, y
+	g.SetTransform(t)
+}
+
+// pivotToCenter moves the pivot on the center of the shapes.
+func (g *Group) pivotToCenter() {
+	g.rwMutex.RLock()
+	x, y := g.childrenBounds(mathgl.Ident4f()).Center()
+	g.rwMutex.RUnlock()
+	g.SetPivot(x, y)
+}
+
+func (g *Group) invalidateWorld() {
+	g.worldValid = false
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	for _, s := range g.children {
+		if c, ok := s.(child); ok {
+			c.invalidateWorld()
+		}
+	}
+}
+
+func (g *Group) boundsIn(m mathgl.Mat4f) Rect {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	return g.childrenBounds(m.Mul4(g.model()))
+}
+
+// childrenBounds returns the union of the bounds of the children
+// transformed by m times their local matrices. The caller must hold
+// the lock.
+func (g *Group) childrenBounds(m mathgl.Mat4f) Rect {
+	r := emptyRect
 	for _, s := range g.children {
-		s.Move(dx, dy)
+		if c, ok := s.(child); ok {
+			r = r.Union(c.boundsIn(m))
+		} else {
+			r = r.Union(s.AABB())
+		}
 	}
-	g.x, g.y = x, y
+	return r
 }
 
 func (g *Group) Vertices() []float32 {
@@ -149,24 +721,78 @@ func (g *Group) Vertices() []float32 {
 	return v
 }
 
-// Center returns the center of the group.
+// Center returns the center of the bounds of the shapes in the group,
+// relative to the parent of the group.
 func (g *Group) Center() (float32, float32) {
-	return g.x, g.y
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	return g.transform.Apply(g.childrenBounds(mathgl.Ident4f()).Center())
 }
 
-// SetCenter sets a new center for the group.
+// SetCenter moves the center of the group in the (x,y) position.
+// It's equivalent to MoveTo.
 func (g *Group) SetCenter(x, y float32) {
-	g.x, g.y = x, y
+	g.MoveTo(x, y)
 }
 
 // Angle returns the rotation angle of the group.
 func (g *Group) Angle() float32 {
-	return g.angle
+	return g.transform.Angle
+}
+
+// TransformedVertices returns the transformed vertices of all the
+// shapes in the group.
+func (g *Group) TransformedVertices() []float32 {
+	v := []float32{}
+
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+
+	for _, s := range g.children {
+		v = append(v, s.TransformedVertices()...)
+	}
+
+	return v
+}
+
+// aabb returns the union of the bounds of the children. The caller
+// must hold the lock.
+func (g *Group) aabb() Rect {
+	r := emptyRect
+	for _, s := range g.children {
+		r = r.Union(s.AABB())
+	}
+	return r
+}
+
+// AABB returns the axis-aligned bounding rectangle of the group,
+// i.e. the union of the bounds of its shapes.
+func (g *Group) AABB() Rect {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	return g.aabb()
+}
+
+// OBB returns the bounding box of the group oriented like the group
+// itself.
+func (g *Group) OBB() OrientedBox {
+	g.rwMutex.RLock()
+	local := g.childrenBounds(mathgl.Ident4f())
+	g.rwMutex.RUnlock()
+	x, y := g.toWorld(g.transform.Apply(local.Center()))
+	angle, sx, sy := g.worldRotationScale()
+	return OrientedBox{
+		X:          x,
+		Y:          y,
+		HalfWidth:  local.Dx() / 2 * mathf.Abs(sx),
+		HalfHeight: local.Dy() / 2 * mathf.Abs(sy),
+		Angle:      angle,
+	}
 }
 
 // Bounds returns the bounding rectangle of the group.
 func (g *Group) Bounds() image.Rectangle {
-	return g.bounds
+	return g.AABB().Image()
 }
 
 // String returns a textual representation of the group.
@@ -181,37 +807,71 @@ func (g *Group) String() string {
 	return str
 }
 
-// AttachToWorld attaches the group to a world.
+// AttachToWorld attaches all the shapes in the group to a world,
+// including the ones appended later.
 func (g *Group) AttachToWorld(world World) {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
 
+	g.attached = world
 	for _, s := range g.children {
 		s.AttachToWorld(world)
 	}
 }
 
-// Clone returns a copy of the group.
+// Clone returns a deep copy of the group, cloning all its shapes.
 func (g *Group) Clone() Shape {
 	g.rwMutex.RLock()
 	defer g.rwMutex.RUnlock()
 
 	cg := NewGroup()
+	cg.SetTransform(g.transform)
+	cg.zIndex = g.zIndex
+	cg.transparency = g.transparency
+	cg.renderer = g.renderer
+	cg.attached = g.attached
+	cg.culling = g.culling
+	cg.index.reset(g.index.size())
 
 	for _, s := range g.children {
-		cs := s.Clone()
-		cg.Append(cs)
+		cg.Append(s.Clone())
 	}
 
-	return g
+	return cg
 }
 
-// SetTexture sets the same texture to all shapes in the group.
-func (g *Group) SetTexture(texture uint32, texCoords []float32) error {
+// SetBlendMode sets the same blend mode to all shapes in the group.
+func (g *Group) SetBlendMode(mode BlendMode) {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
 	for _, s := range g.children {
-		s.SetTexture(texture, texCoords)
+		if b, ok := s.(interface{ SetBlendMode(BlendMode) }); ok {
+			b.SetBlendMode(mode)
+		}
+	}
+}
+
+// SetTexture sets the same texture to all shapes in the group. It
+// returns the first error of the shapes, which are all set anyway.
+func (g *Group) SetTexture(texture *Texture, texCoords []float32) error {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
+	var err error
+	for _, s := range g.children {
+		if e := s.SetTexture(texture, texCoords); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// SetRenderer sets the renderer of all the shapes in the group,
+// including the ones appended later.
+func (g *Group) SetRenderer(renderer Renderer) {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
+	g.renderer = renderer
+	for _, s := range g.children {
+		s.SetRenderer(renderer)
 	}
-	return nil
 }
@@ -0,0 +1,434 @@
+package shapes
+
+import (
+	"math"
+	"sync"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
+
+// maxIndexCells is the number of cells a shape can cover before
+// being kept out of the grid and tested by every query.
+const maxIndexCells = 256
+
+// cell is the position of a cell in the grid of a spatial index.
+type cell struct {
+	x, y int
+}
+
+// indexEntry is a shape held by a spatial index.
+type indexEntry struct {
+	shape Shape
+
+	// child is nil for the shapes that can't report their changes
+	child child
+
+	// Position of the shape in the group, changed only while the
+	// group is locked for writing
+	order int
+
+	// Bounds in the coordinates of the group and range of cells
+	// covered, valid if the entry is placed
+	bounds   Rect
+	from, to cell
+	placed   bool
+	large    bool
+
+	// dirty is true if the entry waits to be placed again
+	dirty bool
+
+	// Last query visiting the entry
+	visited int
+}
+
+// spatialIndex is a uniform grid of square cells holding the shapes
+// of a group. Shapes are bucketed in the cells overlapped by their
+// bounds, in the coordinates of the group, so moving the group
+// doesn't change the index. Shapes report their changes and are
+// placed again lazily before the next query.
+type spatialIndex struct {
+	mutex sync.Mutex
+
+	// Size of the cells, 0 if the index is disabled
+	cellSize float32
+
+	cells   map[cell][]*indexEntry
+	entries map[*node]*indexEntry
+
+	// Shapes covering too many cells, tested by every query
+	large []*indexEntry
+
+	// Shapes that can't report their changes, tested by every
+	// query
+	loose []*indexEntry
+
+	dirty []*indexEntry
+
+	// Range of the cells ever used
+	min, max   cell
+	hasExtents bool
+
+	// Counter of the queries, marking the visited entries
+	query int
+}
+
+// reset empties the index and sets the size of its cells, 0 to
+// disable it.
+func (idx *spatialIndex) reset(cellSize float32) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.cellSize = mathf.Max(cellSize, 0)
+	idx.cells, idx.entries = nil, nil
+	if idx.cellSize > 0 {
+		idx.cells = make(map[cell][]*indexEntry)
+		idx.entries = make(map[*node]*indexEntry)
+	}
+	idx.large, idx.loose, idx.dirty = nil, nil, nil
+	idx.hasExtents = false
+}
+
+// size returns the size of the cells, 0 if the index is disabled.
+func (idx *spatialIndex) size() float32 {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.cellSize
+}
+
+// insert adds the shape to the index. It's placed in the grid at
+// the next query.
+func (idx *spatialIndex) insert(s Shape) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.cellSize == 0 {
+		return
+	}
+	c, ok := s.(child)
+	if !ok {
+		idx.loose = append(idx.loose, &indexEntry{shape: s})
+		return
+	}
+	if old := idx.entries[c.graphNode()]; old != nil {
+		idx.unplace(old)
+	}
+	e := &indexEntry{shape: s, child: c, dirty: true}
+	idx.entries[c.graphNode()] = e
+	idx.dirty = append(idx.dirty, e)
+}
+
+// remove removes the shape from the index.
+func (idx *spatialIndex) remove(s Shape) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.cellSize == 0 {
+		return
+	}
+	c, ok := s.(child)
+	if !ok {
+		for _, e := range idx.loose {
+			if e.shape == s {
+				idx.loose = removeEntry(idx.loose, e)
+				break
+			}
+		}
+		return
+	}
+	n := c.graphNode()
+	if e := idx.entries[n]; e != nil {
+		idx.unplace(e)
+		delete(idx.entries, n)
+	}
+}
+
+// renumber updates the position of the shapes of the group from the
+// given one on.
+func (idx *spatialIndex) renumber(children []Shape, from int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.cellSize == 0 {
+		return
+	}
+	for i := from; i < len(children); i++ {
+		if c, ok := children[i].(child); ok {
+			if e := idx.entries[c.graphNode()]; e != nil {
+				e.order = i
+			}
+			continue
+		}
+		for _, e := range idx.loose {
+			if e.shape == children[i] {
+				e.order = i
+				break
+			}
+		}
+	}
+}
+
+// invalidate marks the shape with the given node for placing again.
+func (idx *spatialIndex) invalidate(n *node) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.cellSize == 0 {
+		return
+	}
+	if e := idx.entries[n]; e != nil && !e.dirty {
+		e.dirty = true
+		idx.dirty = append(idx.dirty, e)
+	}
+}
+
+// refresh places again the shapes that have changed. Their bounds
+// are computed without holding the lock, as nested groups report
+// their changes while holding their own lock.
+func (idx *spatialIndex) refresh() {
+	idx.mutex.Lock()
+	dirty := idx.dirty
+	idx.dirty = nil
+	for _, e := range dirty {
+		e.dirty = false
+	}
+	idx.mutex.Unlock()
+	if len(dirty) == 0 {
+		return
+	}
+
+	bounds := make([]Rect, len(dirty))
+	for i, e := range dirty {
+		bounds[i] = e.child.boundsIn(mathgl.Ident4f())
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	for i, e := range dirty {
+		if idx.entries[e.child.graphNode()] != e {
+			continue
+		}
+		idx.unplace(e)
+		if !bounds[i].Empty() {
+			idx.place(e, bounds[i])
+		}
+	}
+}
+
+// place buckets the entry in the cells overlapped by bounds. The
+// caller must hold the lock.
+func (idx *spatialIndex) place(e *indexEntry, bounds Rect) {
+	e.bounds, e.placed = bounds, true
+	e.from, e.to = idx.cellOf(bounds.MinX, bounds.MinY), idx.cellOf(bounds.MaxX, bounds.MaxY)
+	if (e.to.x-e.from.x+1)*(e.to.y-e.from.y+1) > maxIndexCells {
+		e.large = true
+		idx.large = append(idx.large, e)
+		return
+	}
+	for x := e.from.x; x <= e.to.x; x++ {
+		for y := e.from.y; y <= e.to.y; y++ {
+			c := cell{x, y}
+			idx.cells[c] = append(idx.cells[c], e)
+		}
+	}
+	if !idx.hasExtents {
+		idx.min, idx.max, idx.hasExtents = e.from, e.to, true
+		return
+	}
+	idx.min = cell{mathf.MinInt(idx.min.x, e.from.x), mathf.MinInt(idx.min.y, e.from.y)}
+	idx.max = cell{mathf.MaxInt(idx.max.x, e.to.x), mathf.MaxInt(idx.max.y, e.to.y)}
+}
+
+// unplace removes the entry from the cells. The caller must hold the
+// lock.
+func (idx *spatialIndex) unplace(e *indexEntry) {
+	if !e.placed {
+		return
+	}
+	e.placed = false
+	if e.large {
+		e.large = false
+		idx.large = removeEntry(idx.large, e)
+		return
+	}
+	for x := e.from.x; x <= e.to.x; x++ {
+		for y := e.from.y; y <= e.to.y; y++ {
+			c := cell{x, y}
+			if entries := removeEntry(idx.cells[c], e); len(entries) > 0 {
+				idx.cells[c] = entries
+			} else {
+				delete(idx.cells, c)
+			}
+		}
+	}
+}
+
+// cellOf returns the cell containing the point (x, y).
+func (idx *spatialIndex) cellOf(x, y float32) cell {
+	return cell{int(mathf.Floor(x / idx.cellSize)), int(mathf.Floor(y / idx.cellSize))}
+}
+
+// candidates returns the shapes whose bounds may overlap r, in the
+// coordinates of the group.
+func (idx *spatialIndex) candidates(r Rect) []Shape {
+	entries := idx.entriesIn(r)
+	result := make([]Shape, len(entries))
+	for i, e := range entries {
+		result[i] = e.shape
+	}
+	return result
+}
+
+// entriesIn returns the entries whose bounds may overlap r, in the
+// coordinates of the group.
+func (idx *spatialIndex) entriesIn(r Rect) []*indexEntry {
+	idx.refresh()
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.query++
+	var result []*indexEntry
+	visit := func(e *indexEntry) {
+		if e.visited != idx.query && e.bounds.Overlaps(r) {
+			e.visited = idx.query
+			result = append(result, e)
+		}
+	}
+	if idx.hasExtents && !r.Empty() {
+		from, to := idx.cellOf(r.MinX, r.MinY), idx.cellOf(r.MaxX, r.MaxY)
+		from = cell{mathf.MaxInt(from.x, idx.min.x), mathf.MaxInt(from.y, idx.min.y)}
+		to = cell{mathf.MinInt(to.x, idx.max.x), mathf.MinInt(to.y, idx.max.y)}
+		switch {
+		case from.x > to.x || from.y > to.y:
+			// The query is out of the used cells
+		case (to.x-from.x+1)*(to.y-from.y+1) > len(idx.cells):
+			// Large queries scan the used cells
+			for c, entries := range idx.cells {
+				if c.x >= from.x && c.x <= to.x && c.y >= from.y && c.y <= to.y {
+					for _, e := range entries {
+						visit(e)
+					}
+				}
+			}
+		default:
+			for x := from.x; x <= to.x; x++ {
+				for y := from.y; y <= to.y; y++ {
+					for _, e := range idx.cells[cell{x, y}] {
+						visit(e)
+					}
+				}
+			}
+		}
+	}
+	for _, e := range idx.large {
+		visit(e)
+	}
+	return append(result, idx.loose...)
+}
+
+// nearest returns the shape whose bounds are the nearest to the point
+// (x, y), in the coordinates of the group, and their distance. The
+// shapes that can't report their changes are not considered. The
+// cells are searched in rings of growing distance from the point.
+func (idx *spatialIndex) nearest(x, y float32) (Shape, float32) {
+	idx.refresh()
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.query++
+	var best Shape
+	bestDist := float32(math.Inf(1))
+	visit := func(e *indexEntry) {
+		if e.visited == idx.query {
+			return
+		}
+		e.visited = idx.query
+		if d := rectDistance(e.bounds, x, y); d < bestDist {
+			best, bestDist = e.shape, d
+		}
+	}
+	for _, e := range idx.large {
+		visit(e)
+	}
+	if !idx.hasExtents {
+		return best, bestDist
+	}
+	// Rings around the query cell, from the first one reaching the
+	// used cells to the one containing all of them, clipped to
+	// the used cells
+	c := idx.cellOf(x, y)
+	minRing := mathf.MaxInt(
+		mathf.MaxInt(idx.min.x-c.x, c.x-idx.max.x),
+		mathf.MaxInt(idx.min.y-c.y, c.y-idx.max.y),
+	)
+	minRing = mathf.MaxInt(minRing, 0)
+	maxRing := mathf.MaxInt(
+		mathf.MaxInt(mathf.AbsInt(c.x-idx.min.x), mathf.AbsInt(c.x-idx.max.x)),
+		mathf.MaxInt(mathf.AbsInt(c.y-idx.min.y), mathf.AbsInt(c.y-idx.max.y)),
+	)
+	visitCell := func(x, y int) {
+		for _, e := range idx.cells[cell{x, y}] {
+			visit(e)
+		}
+	}
+	for r := minRing; r <= maxRing; r++ {
+		// The shapes in the ring are at least this far
+		if best != nil && bestDist <= float32(r-1)*idx.cellSize {
+			break
+		}
+		fromX, toX := mathf.MaxInt(c.x-r, idx.min.x), mathf.MinInt(c.x+r, idx.max.x)
+		fromY, toY := mathf.MaxInt(c.y-r, idx.min.y), mathf.MinInt(c.y+r, idx.max.y)
+		for y := fromY; y <= toY; y++ {
+			if y == c.y-r || y == c.y+r {
+				// Top and bottom sides
+				for x := fromX; x <= toX; x++ {
+					visitCell(x, y)
+				}
+				continue
+			}
+			// Left and right sides
+			if c.x-r >= idx.min.x {
+				visitCell(c.x-r, y)
+			}
+			if c.x+r <= idx.max.x {
+				visitCell(c.x+r, y)
+			}
+		}
+	}
+	return best, bestDist
+}
+
+// looseShapes returns the shapes that can't report their changes.
+func (idx *spatialIndex) looseShapes() []Shape {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	result := make([]Shape, len(idx.loose))
+	for i, e := range idx.loose {
+		result[i] = e.shape
+	}
+	return result
+}
+
+// drawnBefore returns true if the shape of a is drawn before the one
+// of b.
+func drawnBefore(a, b *indexEntry) bool {
+	za, zb := a.shape.ZIndex(), b.shape.ZIndex()
+	return za < zb || za == zb && a.order < b.order
+}
+
+// rectDistance returns the distance between the point (x, y) and the
+// rectangle r, 0 if the point lies inside and +Inf if r is empty.
+func rectDistance(r Rect, x, y float32) float32 {
+	if r.Empty() {
+		return float32(math.Inf(1))
+	}
+	dx := mathf.Max(mathf.Max(r.MinX-x, x-r.MaxX), 0)
+	dy := mathf.Max(mathf.Max(r.MinY-y, y-r.MaxY), 0)
+	return float32(math.Sqrt(float64(dx*dx + dy*dy)))
+}
+
+func removeEntry(entries []*indexEntry, e *indexEntry) []*indexEntry {
+	for i, other := range entries {
+		if other == e {
+			copy(entries[i:], entries[i+1:])
+			entries[len(entries)-1] = nil
+			return entries[:len(entries)-1]
+		}
+	}
+	return entries
+}
@@ -0,0 +1,77 @@
+// Package mathf provides the float32 and int helpers shared by the
+// shapes packages.
+package mathf
+
+import "math"
+
+// Floor returns the greatest integer value less than or equal to v.
+func Floor(v float32) float32 {
+	return float32(math.Floor(float64(v)))
+}
+
+// Ceil returns the least integer value greater than or equal to v.
+func Ceil(v float32) float32 {
+	return float32(math.Ceil(float64(v)))
+}
+
+// Abs returns the absolute value of v.
+func Abs(v float32) float32 {
+	return float32(math.Abs(float64(v)))
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b.
+func Max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Clamp limits v to the range [lo, hi].
+func Clamp(v, lo, hi float32) float32 {
+	return Min(Max(v, lo), hi)
+}
+
+// MinInt returns the smaller of a and b.
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MaxInt returns the larger of a and b.
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// AbsInt returns the absolute value of a.
+func AbsInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// SegmentDistance returns the distance between the point (px, py)
+// and the segment (a, b).
+func SegmentDistance(px, py, ax, ay, bx, by float32) float32 {
+	dx, dy := bx-ax, by-ay
+	var k float32
+	if l := dx*dx + dy*dy; l > 0 {
+		k = Clamp(((px-ax)*dx+(py-ay)*dy)/l, 0, 1)
+	}
+	x, y := ax+dx*k-px, ay+dy*k-py
+	return float32(math.Sqrt(float64(x*x + y*y)))
+}
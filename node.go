@@ -0,0 +1,178 @@
+package shapes
+
+import (
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
+
+// node holds the local transform of a shape in the scene graph and
+// the cached matrices derived from it. The local transform is
+// relative to the parent group, the world matrix concatenates the
+// matrices of all the ancestors.
+type node struct {
+	// Position, rotation, scale and pivot relative to the parent
+	transform Transform
+
+	// Matrix of the local transform
+	modelMatrix mathgl.Mat4f
+	modelValid  bool
+
+	// Matrix from shape to world coordinates
+	worldMatrix mathgl.Mat4f
+	worldValid  bool
+
+	// Group containing the shape, nil for root shapes
+	parent *Group
+
+	// Layer of the shape in its group
+	zIndex int
+
+	// Complement of the opacity, so that new shapes are opaque
+	transparency float32
+
+	// World the shape is attached to, its matrices are read at
+	// draw time
+	attached World
+}
+
+// child is implemented by the shapes that can be placed in the scene
+// graph.
+type child interface {
+	// setParent sets the group containing the shape.
+	setParent(g *Group)
+
+	// invalidateWorld marks the world matrix of the shape, and of
+	// its descendants, for rebuilding.
+	invalidateWorld()
+
+	// boundsIn returns the bounds of the shape transformed by m
+	// times its local matrix.
+	boundsIn(m mathgl.Mat4f) Rect
+
+	// graphNode returns the node of the shape in the scene graph.
+	graphNode() *node
+}
+
+// Transform returns the position, rotation, scale and pivot of the
+// shape relative to its parent.
+func (n *node) Transform() Transform {
+	return n.transform
+}
+
+// World returns the world the shape is attached to, nil if the shape
+// is not attached.
+func (n *node) World() World {
+	return n.attached
+}
+
+// Parent returns the group containing the shape, nil if the shape
+// doesn't belong to a group.
+func (n *node) Parent() *Group {
+	return n.parent
+}
+
+// ZIndex returns the layer of the shape in its group.
+func (n *node) ZIndex() int {
+	return n.zIndex
+}
+
+// SetZIndex sets the layer of the shape in its group. Groups draw
+// shapes with lower z-index first, shapes in the same layer in
+// insertion order.
+func (n *node) SetZIndex(z int) {
+	n.zIndex = z
+}
+
+// Opacity returns the opacity of the shape, from 0 (transparent) to 1
+// (opaque).
+func (n *node) Opacity() float32 {
+	return 1 - n.transparency
+}
+
+// SetOpacity sets the opacity of the shape, from 0 (transparent) to 1
+// (opaque). The opacity multiplies the alpha of the colors and of the
+// texture of the shape, and the opacity of the shapes in a group.
+func (n *node) SetOpacity(opacity float32) {
+	n.transparency = 1 - mathf.Clamp(opacity, 0, 1)
+}
+
+// worldOpacity returns the opacity of the shape multiplied by the
+// opacity of its ancestors.
+func (n *node) worldOpacity() float32 {
+	opacity := n.Opacity()
+	for p := n.parent; p != nil; p = p.parent {
+		opacity *= p.Opacity()
+	}
+	return opacity
+}
+
+func (n *node) setParent(g *Group) {
+	n.parent = g
+}
+
+func (n *node) graphNode() *node {
+	return n
+}
+
+// boundsChanged tells the parent that the bounds of the shape in its
+// coordinates have changed.
+func (n *node) boundsChanged() {
+	if n.parent != nil {
+		n.parent.childChanged(n)
+	}
+}
+
+// setTransform replaces the local transform. The caller must
+// invalidate the world matrix.
+func (n *node) setTransform(t Transform) {
+	n.transform = t
+	n.modelValid = false
+	n.boundsChanged()
+}
+
+// model returns the matrix of the local transform, rebuilding it if
+// the transform has changed.
+func (n *node) model() mathgl.Mat4f {
+	if !n.modelValid {
+		n.modelMatrix = n.transform.Matrix()
+		n.modelValid = true
+	}
+	return n.modelMatrix
+}
+
+// world returns the matrix transforming shape coordinates in world
+// coordinates, rebuilding it if the shape or one of its ancestors
+// has changed.
+func (n *node) world() mathgl.Mat4f {
+	if !n.worldValid {
+		n.worldMatrix = n.model()
+		if n.parent != nil {
+			n.worldMatrix = n.parent.world().Mul4(n.worldMatrix)
+		}
+		n.worldValid = true
+	}
+	return n.worldMatrix
+}
+
+// toWorld transforms the point (x, y) from the coordinates of the
+// parent to world coordinates.
+func (n *node) toWorld(x, y float32) (float32, float32) {
+	if n.parent == nil {
+		return x, y
+	}
+	p := n.parent.world().Mul4x1(mathgl.Vec4f{x, y, 0, 1})
+	return p[0], p[1]
+}
+
+// worldRotationScale returns the rotation and the scale of the shape
+// accumulated along its ancestors, ignoring the shear introduced by
+// rotated shapes in groups scaled non-uniformly.
+func (n *node) worldRotationScale() (angle, sx, sy float32) {
+	angle, sx, sy = n.transform.Angle, n.transform.ScaleX, n.transform.ScaleY
+	for p := n.parent; p != nil; p = p.parent {
+		angle += p.transform.Angle
+		sx *= p.transform.ScaleX
+		sy *= p.transform.ScaleY
+	}
+	return angle, sx, sy
+}
@@ -0,0 +1,299 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+
+	"github.com/remogatto/shaders"
+)
+
+// Polygon represents a simple polygon, convex or concave, built from
+// a list of points. The polygon is triangulated by ear clipping and
+// its center is placed on the centroid.
+type Polygon struct {
+	Base
+
+	// Outline of the polygon relative to the centroid
+	points []float32
+}
+
+// NewPolygon creates a new polygon. It takes as arguments a linked
+// program and the (x, y) coordinates of the points of the outline,
+// in clockwise or counter-clockwise order. The outline must not
+// intersect itself. The polygon can be drawn with the default box
+// shaders.
+func NewPolygon(program shaders.Program, points []float32) *Polygon {
+	polygon := new(Polygon)
+	polygon.program = program
+	polygon.primitive = Triangles
+
+	// The polygon is built around its centroid
+	cx, cy := centroid(points)
+	polygon.points = make([]float32, len(points)/2*2)
+	for i := 0; i+1 < len(points); i += 2 {
+		polygon.points[i] = points[i] - cx
+		polygon.points[i+1] = points[i+1] - cy
+	}
+
+	for _, i := range triangulate(polygon.points) {
+		polygon.vertices = append(polygon.vertices, polygon.points[i*2], polygon.points[i*2+1])
+	}
+
+	// Set the default color
+	polygon.SetColor(DefaultColor)
+
+	// Place the centroid
+	polygon.resetTransform(cx, cy)
+
+	return polygon
+}
+
+// Points returns the outline of the polygon relative to its
+// centroid.
+func (p *Polygon) Points() []float32 {
+	return p.points
+}
+
+// Draw actually renders the polygon on the surface.
+func (p *Polygon) Draw() {
+	p.render()
+}
+
+// Clone returns a deep copy of the polygon.
+func (p *Polygon) Clone() Shape {
+	c := new(Polygon)
+	c.points = append([]float32(nil), p.points...)
+	p.cloneInto(&c.Base)
+	return c
+}
+
+// Hulls returns the transformed polygon, split in triangles when it
+// is concave.
+func (p *Polygon) Hulls() [][]float32 {
+	if convex(p.points) {
+		return p.Base.Hulls()
+	}
+	return p.triangleHulls()
+}
+
+// signedArea returns the signed area of the polygon, positive when
+// the points are in counter-clockwise order.
+func signedArea(points []float32) float32 {
+	var area float32
+	n := len(points) / 2
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += points[i*2]*points[j*2+1] - points[j*2]*points[i*2+1]
+	}
+	return area / 2
+}
+
+// centroid returns the centroid of the polygon. The mean of the
+// points is returned for degenerate polygons.
+func centroid(points []float32) (float32, float32) {
+	n := len(points) / 2
+	if n == 0 {
+		return 0, 0
+	}
+	area := signedArea(points)
+	var cx, cy float32
+	if area == 0 {
+		for i := 0; i < n; i++ {
+			cx += points[i*2]
+			cy += points[i*2+1]
+		}
+		return cx / float32(n), cy / float32(n)
+	}
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		cross := points[i*2]*points[j*2+1] - points[j*2]*points[i*2+1]
+		cx += (points[i*2] + points[j*2]) * cross
+		cy += (points[i*2+1] + points[j*2+1]) * cross
+	}
+	return cx / (6 * area), cy / (6 * area)
+}
+
+// triangulate splits a simple polygon in triangles using the ear
+// clipping method. It returns the indices of the points of each
+// triangle, in counter-clockwise order.
+func triangulate(points []float32) []int {
+	n := len(points) / 2
+	if n < 3 {
+		return nil
+	}
+
+	// Work on counter-clockwise ordered indices
+	indices := make([]int, n)
+	clockwise := signedArea(points) < 0
+	for i := range indices {
+		if clockwise {
+			indices[i] = n - 1 - i
+		} else {
+			indices[i] = i
+		}
+	}
+
+	pt := func(i int) (float32, float32) {
+		return points[i*2], points[i*2+1]
+	}
+
+	triangles := make([]int, 0, (n-2)*3)
+	for len(indices) > 3 {
+		clipped := false
+		for k := range indices {
+			prev := indices[(k+len(indices)-1)%len(indices)]
+			cur := indices[k]
+			next := indices[(k+1)%len(indices)]
+			if !isEar(points, indices, prev, cur, next) {
+				continue
+			}
+			triangles = append(triangles, prev, cur, next)
+			indices = append(indices[:k], indices[k+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			// The remaining outline is degenerate (e.g. it
+			// contains collinear points): clip the vertex
+			// with the largest angle to make progress.
+			best, bestCross := 0, float32(math.Inf(-1))
+			for k := range indices {
+				ax, ay := pt(indices[(k+len(indices)-1)%len(indices)])
+				bx, by := pt(indices[k])
+				cx, cy := pt(indices[(k+1)%len(indices)])
+				if c := cross(ax, ay, bx, by, cx, cy); c > bestCross {
+					best, bestCross = k, c
+				}
+			}
+			prev := indices[(best+len(indices)-1)%len(indices)]
+			next := indices[(best+1)%len(indices)]
+			triangles = append(triangles, prev, indices[best], next)
+			indices = append(indices[:best], indices[best+1:]...)
+		}
+	}
+	return append(triangles, indices...)
+}
+
+// isEar returns true if the triangle (prev, cur, next) is convex and
+// doesn't contain any other point of the outline.
+func isEar(points []float32, indices []int, prev, cur, next int) bool {
+	ax, ay := points[prev*2], points[prev*2+1]
+	bx, by := points[cur*2], points[cur*2+1]
+	cx, cy := points[next*2], points[next*2+1]
+	if cross(ax, ay, bx, by, cx, cy) <= 0 {
+		return false
+	}
+	for _, i := range indices {
+		if i == prev || i == cur || i == next {
+			continue
+		}
+		if pointInTriangle(points[i*2], points[i*2+1], ax, ay, bx, by, cx, cy) {
+			return false
+		}
+	}
+	return true
+}
+
+// cross returns the z component of the cross product of the vectors
+// (b - a) and (c - b).
+func cross(ax, ay, bx, by, cx, cy float32) float32 {
+	return (bx-ax)*(cy-by) - (by-ay)*(cx-bx)
+}
+
+// convex returns true if the outline turns always in the same
+// direction.
+func convex(points []float32) bool {
+	n := len(points) / 2
+	var sign float32
+	for i := 0; i < n; i++ {
+		j, k := (i+1)%n, (i+2)%n
+		c := cross(points[i*2], points[i*2+1], points[j*2], points[j*2+1], points[k*2], points[k*2+1])
+		if c == 0 {
+			continue
+		}
+		if sign*c < 0 {
+			return false
+		}
+		sign = c
+	}
+	return true
+}
+
+// convexHull returns the convex hull of the given (x, y) points in
+// counter-clockwise order, computed with the monotone chain
+// algorithm. Collinear points on the hull are dropped.
+func convexHull(points []float32) []float32 {
+	n := len(points) / 2
+	pts := make([][2]float32, n)
+	for i := range pts {
+		pts[i] = [2]float32{points[i*2], points[i*2+1]}
+	}
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i][0] < pts[j][0] || (pts[i][0] == pts[j][0] && pts[i][1] < pts[j][1])
+	})
+	// Drop duplicated points
+	k := 0
+	for i := range pts {
+		if i == 0 || pts[i] != pts[k-1] {
+			pts[k] = pts[i]
+			k++
+		}
+	}
+	pts = pts[:k]
+	if len(pts) < 3 {
+		hull := make([]float32, 0, len(pts)*2)
+		for _, p := range pts {
+			hull = append(hull, p[0], p[1])
+		}
+		return hull
+	}
+
+	hull := make([][2]float32, 0, len(pts)+1)
+	build := func(p [2]float32, min int) {
+		for len(hull) >= min {
+			a, b := hull[len(hull)-2], hull[len(hull)-1]
+			if cross(a[0], a[1], b[0], b[1], p[0], p[1]) > 0 {
+				break
+			}
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// Lower hull
+	for _, p := range pts {
+		build(p, 2)
+	}
+	// Upper hull
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		build(pts[i], lower)
+	}
+	hull = hull[:len(hull)-1]
+
+	result := make([]float32, 0, len(hull)*2)
+	for _, p := range hull {
+		result = append(result, p[0], p[1])
+	}
+	return result
+}
+
+// insideTriangle returns true if (px, py) lies inside or on the
+// border of the triangle (a, b, c), whatever its winding. Degenerate
+// triangles contain no points.
+func insideTriangle(px, py, ax, ay, bx, by, cx, cy float32) bool {
+	if cross(ax, ay, bx, by, cx, cy) == 0 {
+		return false
+	}
+	d0 := cross(ax, ay, bx, by, px, py)
+	d1 := cross(bx, by, cx, cy, px, py)
+	d2 := cross(cx, cy, ax, ay, px, py)
+	return (d0 >= 0 && d1 >= 0 && d2 >= 0) || (d0 <= 0 && d1 <= 0 && d2 <= 0)
+}
+
+// pointInTriangle returns true if (px, py) lies inside or on the
+// border of the counter-clockwise triangle (a, b, c).
+func pointInTriangle(px, py, ax, ay, bx, by, cx, cy float32) bool {
+	return cross(ax, ay, bx, by, px, py) >= 0 &&
+		cross(bx, by, cx, cy, px, py) >= 0 &&
+		cross(cx, cy, ax, ay, px, py) >= 0
+}
@@ -0,0 +1,126 @@
+package shapes
+
+import "github.com/remogatto/shaders"
+
+// Polyline represents a line through a list of points, stroked with
+// a given width. The line is rendered as triangles so that it looks
+// the same on every driver.
+type Polyline struct {
+	Base
+
+	// Points of the line relative to the center
+	points []float32
+
+	// Stroke style
+	width  float32
+	join   LineJoin
+	cap    LineCap
+	closed bool
+}
+
+// NewPolyline creates a new polyline. It takes as arguments a linked
+// program, the (x, y) coordinates of the points and the width of the
+// stroke. The line is joined with MiterJoin and capped with ButtCap.
+// It can be drawn with the default segment shaders.
+func NewPolyline(program shaders.Program, points []float32, width float32) *Polyline {
+	polyline := new(Polyline)
+	polyline.program = program
+	polyline.primitive = Triangles
+	polyline.width = width
+
+	// Center of the polyline
+	cx, cy := rectOf(points).Center()
+
+	polyline.points = make([]float32, len(points)/2*2)
+	for i := 0; i+1 < len(points); i += 2 {
+		polyline.points[i] = points[i] - cx
+		polyline.points[i+1] = points[i+1] - cy
+	}
+
+	polyline.vertices = stroke(polyline.points, width, polyline.join, polyline.cap, false)
+
+	// Set the default color
+	polyline.SetColor(DefaultColor)
+
+	// Place the center
+	polyline.resetTransform(cx, cy)
+
+	return polyline
+}
+
+// restroke rebuilds the triangles of the line after a change of the
+// stroke style.
+func (p *Polyline) restroke() {
+	p.vertices = stroke(p.points, p.width, p.join, p.cap, p.closed)
+	p.verticesChanged()
+}
+
+// Points returns the points of the line relative to its center.
+func (p *Polyline) Points() []float32 {
+	return p.points
+}
+
+// Width returns the width of the stroke.
+func (p *Polyline) Width() float32 {
+	return p.width
+}
+
+// SetWidth sets the width of the stroke.
+func (p *Polyline) SetWidth(width float32) {
+	p.width = width
+	p.restroke()
+}
+
+// Join returns the style used to join the segments.
+func (p *Polyline) Join() LineJoin {
+	return p.join
+}
+
+// SetJoin sets the style used to join the segments.
+func (p *Polyline) SetJoin(join LineJoin) {
+	p.join = join
+	p.restroke()
+}
+
+// Cap returns the style used at the ends of the line.
+func (p *Polyline) Cap() LineCap {
+	return p.cap
+}
+
+// SetCap sets the style used at the ends of the line.
+func (p *Polyline) SetCap(cap LineCap) {
+	p.cap = cap
+	p.restroke()
+}
+
+// Closed returns true if the last point is joined with the first
+// one.
+func (p *Polyline) Closed() bool {
+	return p.closed
+}
+
+// SetClosed sets whether the last point is joined with the first
+// one. Closed lines have no caps.
+func (p *Polyline) SetClosed(closed bool) {
+	p.closed = closed
+	p.restroke()
+}
+
+// Hulls returns the transformed triangles of the stroked polyline.
+func (p *Polyline) Hulls() [][]float32 {
+	return p.triangleHulls()
+}
+
+// Draw actually renders the polyline on the surface.
+func (p *Polyline) Draw() {
+	p.render()
+}
+
+// Clone returns a deep copy of the polyline.
+func (p *Polyline) Clone() Shape {
+	c := new(Polyline)
+	c.points = append([]float32(nil), p.points...)
+	c.width, c.join, c.cap, c.closed = p.width, p.join, p.cap, p.closed
+	p.cloneInto(&c.Base)
+	return c
+}
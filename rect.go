@@ -0,0 +1,151 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/aded/shapes/internal/mathf"
+)
+
+// Rect is an axis-aligned rectangle with float32 coordinates. It
+// contains the points with MinX <= x <= MaxX and MinY <= y <= MaxY.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float32
+}
+
+// emptyRect contains no points. It's the bounds of the shapes
+// without vertices and it's ignored by Union.
+var emptyRect = Rect{
+	MinX: float32(math.Inf(1)), MinY: float32(math.Inf(1)),
+	MaxX: float32(math.Inf(-1)), MaxY: float32(math.Inf(-1)),
+}
+
+// rectOf returns the smallest rectangle containing the given (x, y)
+// points, an empty rectangle if there are no points.
+func rectOf(points []float32) Rect {
+	if len(points) < 2 {
+		return emptyRect
+	}
+	r := Rect{points[0], points[1], points[0], points[1]}
+	for i := 2; i+1 < len(points); i += 2 {
+		r.MinX, r.MaxX = mathf.Min(r.MinX, points[i]), mathf.Max(r.MaxX, points[i])
+		r.MinY, r.MaxY = mathf.Min(r.MinY, points[i+1]), mathf.Max(r.MaxY, points[i+1])
+	}
+	return r
+}
+
+// Dx returns the width of the rectangle, 0 if it's empty.
+func (r Rect) Dx() float32 {
+	if r.Empty() {
+		return 0
+	}
+	return r.MaxX - r.MinX
+}
+
+// Dy returns the height of the rectangle, 0 if it's empty.
+func (r Rect) Dy() float32 {
+	if r.Empty() {
+		return 0
+	}
+	return r.MaxY - r.MinY
+}
+
+// Center returns the center of the rectangle, the origin if it's
+// empty.
+func (r Rect) Center() (float32, float32) {
+	if r.Empty() {
+		return 0, 0
+	}
+	return (r.MinX + r.MaxX) / 2, (r.MinY + r.MaxY) / 2
+}
+
+// Empty returns true if the rectangle contains no points.
+func (r Rect) Empty() bool {
+	return r.MinX > r.MaxX || r.MinY > r.MaxY
+}
+
+// Contains returns true if the point (x, y) lies in the rectangle.
+func (r Rect) Contains(x, y float32) bool {
+	return x >= r.MinX && x <= r.MaxX && y >= r.MinY && y <= r.MaxY
+}
+
+// Overlaps returns true if r and s have at least a point in common.
+func (r Rect) Overlaps(s Rect) bool {
+	return !r.Empty() && !s.Empty() &&
+		r.MinX <= s.MaxX && s.MinX <= r.MaxX &&
+		r.MinY <= s.MaxY && s.MinY <= r.MaxY
+}
+
+// Union returns the smallest rectangle containing both r and s.
+func (r Rect) Union(s Rect) Rect {
+	if r.Empty() {
+		return s
+	}
+	if s.Empty() {
+		return r
+	}
+	return Rect{
+		mathf.Min(r.MinX, s.MinX), mathf.Min(r.MinY, s.MinY),
+		mathf.Max(r.MaxX, s.MaxX), mathf.Max(r.MaxY, s.MaxY),
+	}
+}
+
+// Intersect returns the largest rectangle contained by both r and
+// s. The result may be empty.
+func (r Rect) Intersect(s Rect) Rect {
+	return Rect{
+		mathf.Max(r.MinX, s.MinX), mathf.Max(r.MinY, s.MinY),
+		mathf.Min(r.MaxX, s.MaxX), mathf.Min(r.MaxY, s.MaxY),
+	}
+}
+
+// Image returns the smallest image.Rectangle containing r, the zero
+// rectangle if r is empty.
+func (r Rect) Image() image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	return image.Rect(
+		int(mathf.Floor(r.MinX)), int(mathf.Floor(r.MinY)),
+		int(mathf.Ceil(r.MaxX)), int(mathf.Ceil(r.MaxY)),
+	)
+}
+
+// String returns a string representation of the rectangle.
+func (r Rect) String() string {
+	return fmt.Sprintf("(%g,%g)-(%g,%g)", r.MinX, r.MinY, r.MaxX, r.MaxY)
+}
+
+// OrientedBox is a rectangle rotated around its center.
+type OrientedBox struct {
+	// Center of the box
+	X, Y float32
+
+	// Half sizes along the axes of the box
+	HalfWidth, HalfHeight float32
+
+	// Angle in degrees
+	Angle float32
+}
+
+// Corners returns the (x, y) coordinates of the corners of the box,
+// in counter-clockwise order.
+func (o OrientedBox) Corners() []float32 {
+	corners := []float32{
+		-o.HalfWidth, -o.HalfHeight,
+		o.HalfWidth, -o.HalfHeight,
+		o.HalfWidth, o.HalfHeight,
+		-o.HalfWidth, o.HalfHeight,
+	}
+	for i := 0; i < len(corners); i += 2 {
+		x, y := rotate(corners[i], corners[i+1], o.Angle)
+		corners[i], corners[i+1] = x+o.X, y+o.Y
+	}
+	return corners
+}
+
+// AABB returns the axis-aligned bounding rectangle of the box.
+func (o OrientedBox) AABB() Rect {
+	return rectOf(o.Corners())
+}
@@ -0,0 +1,107 @@
+package shapes
+
+import (
+	"github.com/remogatto/mathgl"
+	"github.com/remogatto/shaders"
+)
+
+// Primitive is the kind of primitive used to assemble the vertices
+// of a shape.
+type Primitive int
+
+const (
+	// Triangles draws a separate triangle for every three
+	// vertices.
+	Triangles Primitive = iota
+
+	// TriangleStrip draws a connected strip of triangles.
+	TriangleStrip
+
+	// TriangleFan draws a fan of triangles sharing the first
+	// vertex.
+	TriangleFan
+
+	// Lines draws a separate line for every two vertices.
+	Lines
+)
+
+// DrawCommand holds everything a renderer needs in order to draw a
+// shape.
+type DrawCommand struct {
+	// Program is the GLSL program used by the shape
+	Program shaders.Program
+
+	// Primitive used to assemble the vertices
+	Primitive Primitive
+
+	// Vertices contains two components for each vertex
+	Vertices []float32
+
+	// Colors contains four normalized components for each vertex
+	Colors []float32
+
+	// TexCoords contains two components for each vertex. It's
+	// empty when the shape is not textured.
+	TexCoords []float32
+
+	// Texture is the texture bound when TexCoords is not empty
+	Texture *Texture
+
+	// Matrices
+	Model, Projection, View mathgl.Mat4f
+
+	// Opacity multiplies the alpha of the colors and the texture
+	Opacity float32
+
+	// Blend is the way the shape is combined with the background
+	Blend BlendMode
+
+	// Buffers caches the attributes on the GPU. If nil the
+	// attributes are uploaded at each draw.
+	Buffers *Buffers
+}
+
+// Renderer is the interface implemented by rendering backends.
+// Shapes don't talk to OpenGL directly, they build a DrawCommand and
+// hand it to their renderer.
+type Renderer interface {
+	// Render draws the given command.
+	Render(cmd *DrawCommand)
+}
+
+// StateRestorer is implemented by renderers changing the state of the
+// context they draw on. Begin saves the state and End restores it.
+// Groups draw their shapes between Begin and End.
+type StateRestorer interface {
+	Begin()
+	End()
+}
+
+// DefaultRenderer is the renderer used by shapes that have not been
+// given one with SetRenderer.
+var DefaultRenderer Renderer = NewGLRenderer()
+
+// eachTriangle calls fn with the indices of every triangle
+// assembled from count vertices using the primitive p. Triangles
+// are reported with a consistent winding. It does nothing for line
+// primitives.
+func eachTriangle(p Primitive, count int, fn func(i0, i1, i2 int)) {
+	switch p {
+	case Triangles:
+		for i := 0; i+2 < count; i += 3 {
+			fn(i, i+1, i+2)
+		}
+	case TriangleStrip:
+		for i := 0; i+2 < count; i++ {
+			if i%2 == 0 {
+				fn(i, i+1, i+2)
+			} else {
+				fn(i+1, i, i+2)
+			}
+		}
+	case TriangleFan:
+		for i := 1; i+1 < count; i++ {
+			fn(0, i, i+1)
+		}
+	}
+}
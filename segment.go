@@ -1,10 +1,8 @@
 package shapes
 
 import (
-	"image"
-
+	"github.com/aded/shapes/internal/mathf"
 	"github.com/remogatto/mathgl"
-	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shaders"
 )
 
@@ -19,7 +17,7 @@ var (
                  uniform mat4 projection;
                  uniform mat4 view;
                  void main() {
-                     gl_Position = projection*model*view*pos;
+                     gl_Position = projection*view*model*pos;
                      vColor = color;
                  }`)
 
@@ -28,8 +26,9 @@ var (
 	DefaultSegmentFS = (shaders.FragmentShader)(
 		`precision mediump float;
                  varying vec4 vColor;
+                 uniform float opacity;
                  void main() {
-                     gl_FragColor = vColor;
+                     gl_FragColor = vec4(vColor.rgb * vColor.a, vColor.a) * opacity;
                  }`)
 )
 
@@ -37,20 +36,35 @@ var (
 type Segment struct {
 	Base
 
-	// Points of the segment
+	// Points of the segment relative to its center
 	x1, y1, x2, y2 float32
+
+	// Width of the stroke, 0 for a hairline
+	width float32
+
+	// Maximum distance of the points contained by the segment
+	tolerance float32
 }
 
+// DefaultSegmentTolerance is the default maximum distance, in world
+// units, between a segment and the points it contains.
+var DefaultSegmentTolerance float32 = 4
+
 // NewSegment returns a new segment object. It takes a program
 // shader and segment coordinates as arguments.
 func NewSegment(program shaders.Program, x1, y1, x2, y2 float32) *Segment {
 
 	segment := new(Segment)
+	segment.primitive = Lines
+	segment.tolerance = DefaultSegmentTolerance
+
+	// Center of the segment
+	cx, cy := (x1+x2)/2, (y1+y2)/2
 
-	// Set the geometry
+	// Set the geometry around the center
 
-	segment.x1, segment.x2 = x1, x2
-	segment.y1, segment.y2 = y1, y2
+	segment.x1, segment.x2 = x1-cx, x2-cx
+	segment.y1, segment.y2 = y1-cy, y2-cy
 
 	segment.vertices = []float32{
 		segment.x1, segment.y1,
@@ -60,46 +74,76 @@ func NewSegment(program shaders.Program, x1, y1, x2, y2 float32) *Segment {
 	// Set the default color
 	segment.SetColor(DefaultColor)
 
-	// Size of the segment bounding box
-	// segment.w = float32(math.Abs(float64(x1 - x2)))
-	// segment.h = float32(math.Abs(float64(y1 - y2)))
-	segment.bounds = image.Rect(int(x1), int(y1), int(x2), int(y2))
-
-	// Center of the segment
-	segment.x = (segment.x1 + segment.x2) / 2
-	segment.y = (segment.y1 + segment.y2) / 2
-
 	segment.program = program
-	segment.program.Use()
 
-	// Get variables IDs from shaders
-	segment.posId = segment.program.GetAttribute("pos")
-	segment.colorId = segment.program.GetAttribute("color")
-	segment.projMatrixId = segment.program.GetUniform("projection")
-	segment.modelMatrixId = segment.program.GetUniform("model")
-	segment.viewMatrixId = segment.program.GetUniform("view")
-
-	// Fill the model matrix with the identity.
-	segment.modelMatrix = mathgl.Ident4f()
+	// Place the center of the segment.
+	segment.resetTransform(cx, cy)
 
 	return segment
 }
 
-// Draw actually renders the segment on the surface.
-func (segment *Segment) Draw() {
-	segment.program.Use()
-	gl.VertexAttribPointer(segment.posId, 2, gl.FLOAT, false, 0, &segment.vertices[0])
-	gl.EnableVertexAttribArray(segment.posId)
+// Width returns the width of the segment. A zero width means a one
+// pixel wide line.
+func (segment *Segment) Width() float32 {
+	return segment.width
+}
+
+// SetWidth sets the width of the segment. Segments wider than zero
+// are stroked like a Polyline, a zero width restores the one pixel
+// wide line.
+func (segment *Segment) SetWidth(width float32) {
+	segment.width = width
+	if width > 0 {
+		segment.primitive = Triangles
+		segment.vertices = stroke(
+			[]float32{segment.x1, segment.y1, segment.x2, segment.y2},
+			width, MiterJoin, ButtCap, false,
+		)
+	} else {
+		segment.primitive = Lines
+		segment.vertices = []float32{
+			segment.x1, segment.y1,
+			segment.x2, segment.y2,
+		}
+	}
+	segment.verticesChanged()
+}
+
+// Tolerance returns the maximum distance between the segment and the
+// points it contains.
+func (segment *Segment) Tolerance() float32 {
+	return segment.tolerance
+}
 
-	gl.VertexAttribPointer(segment.colorId, 4, gl.FLOAT, false, 0, &segment.vColor[0])
-	gl.EnableVertexAttribArray(segment.colorId)
+// SetTolerance sets the maximum distance between the segment and the
+// points it contains.
+func (segment *Segment) SetTolerance(tolerance float32) {
+	segment.tolerance = tolerance
+}
 
-	gl.UniformMatrix4fv(int32(segment.modelMatrixId), 1, false, (*float32)(&segment.modelMatrix[0]))
-	gl.UniformMatrix4fv(int32(segment.projMatrixId), 1, false, (*float32)(&segment.projMatrix[0]))
-	gl.UniformMatrix4fv(int32(segment.viewMatrixId), 1, false, (*float32)(&segment.viewMatrix[0]))
+// Contains returns true if the point (x, y) lies on the transformed
+// segment, or on its stroke for wide segments, within the tolerance.
+func (segment *Segment) Contains(x, y float32) bool {
+	if segment.width > 0 && segment.Base.Contains(x, y) {
+		return true
+	}
+	m := segment.world()
+	p1 := m.Mul4x1(mathgl.Vec4f{segment.x1, segment.y1, 0, 1})
+	p2 := m.Mul4x1(mathgl.Vec4f{segment.x2, segment.y2, 0, 1})
+	return mathf.SegmentDistance(x, y, p1[0], p1[1], p2[0], p2[1]) <= segment.tolerance
+}
 
-	gl.DrawArrays(gl.LINES, 0, 2)
+// Draw actually renders the segment on the surface.
+func (segment *Segment) Draw() {
+	segment.render()
+}
 
-	gl.Flush()
-	gl.Finish()
+// Clone returns a deep copy of the segment.
+func (segment *Segment) Clone() Shape {
+	c := new(Segment)
+	c.x1, c.y1, c.x2, c.y2 = segment.x1, segment.y1, segment.x2, segment.y2
+	c.width = segment.width
+	c.tolerance = segment.tolerance
+	segment.cloneInto(&c.Base)
+	return c
 }
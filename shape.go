@@ -28,9 +28,39 @@ type Shape interface {
 	// MoveTo moves the (center of the) shape in position (x,y).
 	MoveTo(x, y float32)
 
+	// Transform returns the position, rotation, scale and pivot
+	// of the shape relative to its parent.
+	Transform() Transform
+
+	// SetTransform sets the position, rotation, scale and pivot
+	// of the shape relative to its parent.
+	SetTransform(t Transform)
+
+	// Parent returns the group containing the shape, nil if the
+	// shape doesn't belong to a group.
+	Parent() *Group
+
+	// ZIndex returns the layer of the shape in its group.
+	ZIndex() int
+
+	// SetZIndex sets the layer of the shape in its group.
+	// Shapes with higher z-index are drawn on top.
+	SetZIndex(z int)
+
+	// Opacity returns the opacity of the shape.
+	Opacity() float32
+
+	// SetOpacity sets the opacity of the shape, from 0
+	// (transparent) to 1 (opaque).
+	SetOpacity(opacity float32)
+
 	// Draw renders the shape on the surface.
 	Draw()
 
+	// Release deletes the OpenGL resources allocated for the
+	// shape.
+	Release()
+
 	// Vertices returns the vertices slice of the shape.
 	Vertices() []float32
 
@@ -40,9 +70,30 @@ type Shape interface {
 	// Angle returns the rotation angle of the shape.
 	Angle() float32
 
-	// Bounds returns the bounding rectangle of the shape.
+	// TransformedVertices returns the vertices of the shape
+	// transformed by its model matrix.
+	TransformedVertices() []float32
+
+	// AABB returns the axis-aligned bounding rectangle of the
+	// transformed shape.
+	AABB() Rect
+
+	// OBB returns the bounding box of the shape oriented like
+	// the shape itself.
+	OBB() OrientedBox
+
+	// Bounds returns the smallest Rectangle containing the
+	// bounding rectangle of the shape.
 	Bounds() image.Rectangle
 
+	// Contains returns true if the point (x, y), in world
+	// coordinates, lies inside the transformed shape.
+	Contains(x, y float32) bool
+
+	// Hulls returns the transformed shape split in convex
+	// polygons, each one a list of (x, y) coordinates.
+	Hulls() [][]float32
+
 	// String returns a string representation of the shape.
 	String() string
 
@@ -52,6 +103,10 @@ type Shape interface {
 	// Clone clones the current shape and returns a new shape.
 	Clone() Shape
 
-	// SetTexture sets a texture for the shape.
-	SetTexture(texture uint32, texCoords []float32) error
+	// SetTexture sets a texture for the shape. It returns an
+	// error if the texture coordinates don't match the vertices.
+	SetTexture(texture *Texture, texCoords []float32) error
+
+	// SetRenderer sets the renderer used to draw the shape.
+	SetRenderer(renderer Renderer)
 }
@@ -0,0 +1,212 @@
+package shapes
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
+
+// SoftwareRenderer is a pure-Go renderer rasterizing shapes into an
+// *image.RGBA. It doesn't need an OpenGL context, so it can be used
+// to render shapes headlessly (e.g. for testing). It mimics the
+// default shaders: fragments are colored with the interpolated
+// vertex colors or, when texture coordinates are given, with the
+// nearest texel of the texture, and blended with the image as
+// OpenGL does.
+type SoftwareRenderer struct {
+	img *image.RGBA
+
+	// Blending of the command being rendered. Premultiplied is
+	// true if the texture is premultiplied by alpha.
+	blend         BlendMode
+	premultiplied bool
+	opacity       float32
+}
+
+// swVertex is a vertex transformed in window coordinates.
+type swVertex struct {
+	x, y  float32
+	color [4]float32
+	s, t  float32
+}
+
+// NewSoftwareRenderer returns a renderer drawing on a new image of
+// the given size.
+func NewSoftwareRenderer(width, height int) *SoftwareRenderer {
+	return &SoftwareRenderer{
+		img: image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+}
+
+// Image returns the image the renderer draws on.
+func (r *SoftwareRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+// Clear fills the whole image with the given color.
+func (r *SoftwareRenderer) Clear(c color.Color) {
+	draw.Draw(r.img, r.img.Bounds(), &image.Uniform{c}, image.ZP, draw.Src)
+}
+
+// Render rasterizes the command on the image.
+func (r *SoftwareRenderer) Render(cmd *DrawCommand) {
+	count := len(cmd.Vertices) / 2
+	if count == 0 {
+		return
+	}
+
+	// Same transformation of the default vertex shaders
+	mvp := cmd.Projection.Mul4(cmd.View).Mul4(cmd.Model)
+
+	// Textures created by the client code have no pixels
+	var texture *Texture
+	if len(cmd.TexCoords) > 0 && cmd.Texture != nil && cmd.Texture.pix != nil {
+		texture = cmd.Texture
+	}
+	r.blend, r.opacity = cmd.Blend, cmd.Opacity
+	r.premultiplied = cmd.Blend == PremultipliedBlend || texture != nil && texture.options.PremultipliedAlpha
+
+	size := r.img.Bounds().Size()
+	w, h := float32(size.X), float32(size.Y)
+
+	vertices := make([]swVertex, count)
+	for i := range vertices {
+		p := mvp.Mul4x1(mathgl.Vec4f{cmd.Vertices[i*2], cmd.Vertices[i*2+1], 0, 1})
+		v := &vertices[i]
+		// From normalized device coordinates to image
+		// coordinates, the y axis points down in the image.
+		v.x = (p[0]/p[3] + 1) / 2 * w
+		v.y = h - (p[1]/p[3]+1)/2*h
+		copy(v.color[:], cmd.Colors[i*4:i*4+4])
+		if texture != nil {
+			v.s, v.t = cmd.TexCoords[i*2], cmd.TexCoords[i*2+1]
+		}
+	}
+
+	if cmd.Primitive == Lines {
+		for i := 0; i+1 < count; i += 2 {
+			r.line(&vertices[i], &vertices[i+1])
+		}
+		return
+	}
+
+	eachTriangle(cmd.Primitive, count, func(i0, i1, i2 int) {
+		r.triangle(&vertices[i0], &vertices[i1], &vertices[i2], texture)
+	})
+}
+
+// triangle rasterizes a triangle interpolating colors and texture
+// coordinates with barycentric weights. A pixel is covered when its
+// center lies inside the triangle. As in OpenGL, centers on an edge
+// shared by two triangles are covered by one of them only: the one
+// having the edge on its top or left side.
+func (r *SoftwareRenderer) triangle(v0, v1, v2 *swVertex, texture *Texture) {
+	area := edge(v0, v1, v2.x, v2.y)
+	if area == 0 {
+		return
+	}
+	// Wind the triangle so that the inside of each edge is
+	// positive
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+	topLeft0, topLeft1, topLeft2 := topLeft(v1, v2), topLeft(v2, v0), topLeft(v0, v1)
+
+	// Scan the bounding box of the triangle, clipped to the image
+	bounds := r.img.Bounds().Intersect(image.Rect(
+		int(mathf.Floor(mathf.Min(v0.x, mathf.Min(v1.x, v2.x)))),
+		int(mathf.Floor(mathf.Min(v0.y, mathf.Min(v1.y, v2.y)))),
+		int(mathf.Ceil(mathf.Max(v0.x, mathf.Max(v1.x, v2.x)))),
+		int(mathf.Ceil(mathf.Max(v0.y, mathf.Max(v1.y, v2.y)))),
+	))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px, py := float32(x)+0.5, float32(y)+0.5
+			e0, e1, e2 := edge(v1, v2, px, py), edge(v2, v0, px, py), edge(v0, v1, px, py)
+			if !covers(e0, topLeft0) || !covers(e1, topLeft1) || !covers(e2, topLeft2) {
+				continue
+			}
+			w0, w1, w2 := e0/area, e1/area, e2/area
+			var c [4]float32
+			if texture != nil {
+				s := w0*v0.s + w1*v1.s + w2*v2.s
+				t := w0*v0.t + w1*v1.t + w2*v2.t
+				c = premultiply(texture.texel(s, t), r.premultiplied, r.opacity)
+			} else {
+				for i := range c {
+					c[i] = w0*v0.color[i] + w1*v1.color[i] + w2*v2.color[i]
+				}
+				c = premultiply(c, false, r.opacity)
+			}
+			r.set(x, y, c)
+		}
+	}
+}
+
+// line rasterizes a one pixel wide line interpolating colors.
+func (r *SoftwareRenderer) line(v0, v1 *swVertex) {
+	dx, dy := v1.x-v0.x, v1.y-v0.y
+	steps := int(mathf.Ceil(mathf.Max(mathf.Abs(dx), mathf.Abs(dy))))
+	if steps == 0 {
+		steps = 1
+	}
+	for i := 0; i <= steps; i++ {
+		k := float32(i) / float32(steps)
+		var c [4]float32
+		for j := range c {
+			c[j] = v0.color[j] + (v1.color[j]-v0.color[j])*k
+		}
+		c = premultiply(c, false, r.opacity)
+		r.set(int(mathf.Floor(v0.x+dx*k)), int(mathf.Floor(v0.y+dy*k)), c)
+	}
+}
+
+// set blends a normalized color, premultiplied by alpha, on the pixel
+// at (x, y).
+func (r *SoftwareRenderer) set(x, y int, c [4]float32) {
+	if !(image.Point{x, y}).In(r.img.Bounds()) {
+		return
+	}
+	i := r.img.PixOffset(x, y)
+	var dst [4]float32
+	for j := range dst {
+		dst[j] = float32(r.img.Pix[i+j]) / 255
+	}
+	c = blend(r.blend, c, dst)
+	for j := range c {
+		r.img.Pix[i+j] = uint8(mathf.Clamp(c[j], 0, 1)*255 + 0.5)
+	}
+}
+
+// edge returns the signed area of the parallelogram built on the
+// edge (a, b) and the point (x, y).
+func edge(a, b *swVertex, x, y float32) float32 {
+	// Evaluate the edge always from the same end, so that the
+	// triangles sharing it get exactly opposite values
+	if b.x < a.x || b.x == a.x && b.y < a.y {
+		return -edgeFrom(b, a, x, y)
+	}
+	return edgeFrom(a, b, x, y)
+}
+
+func edgeFrom(a, b *swVertex, x, y float32) float32 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// topLeft returns true if the edge from a to b is a top or a left
+// edge of a triangle lying on its positive side.
+func topLeft(a, b *swVertex) bool {
+	dx, dy := b.x-a.x, b.y-a.y
+	return dy < 0 || dy == 0 && dx > 0
+}
+
+// covers returns true if a pixel center at the value e of an edge
+// function lies inside the triangle.
+func covers(e float32, topLeft bool) bool {
+	return e > 0 || e == 0 && topLeft
+}
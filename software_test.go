@@ -0,0 +1,194 @@
+package shapes
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/remogatto/mathgl"
+)
+
+const (
+	// Size of the window where the expected images were taken
+	testWidth, testHeight = 320, 480
+
+	// Folder of the expected images
+	expectedPath = "test/android/res/drawable"
+
+	// Largest difference of a color component for two pixels to
+	// be considered equal, as the rasterization of the edges and
+	// the sampling of the textures differ slightly from OpenGL
+	pixelTolerance = 40
+)
+
+// testWorld is the world of the expected images: the origin is on the
+// left side, halfway up the window.
+type testWorld struct{}
+
+func (testWorld) Projection() mathgl.Mat4f {
+	return mathgl.Ortho2D(0, testWidth, -testHeight/2, testHeight/2)
+}
+
+func (testWorld) View() mathgl.Mat4f { return mathgl.Ident4f() }
+
+// loadImage decodes a PNG image from the folder of the expected
+// images.
+func loadImage(t *testing.T, filename string) image.Image {
+	file, err := os.Open(filepath.Join(expectedPath, filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// loadTestTexture loads the gopher texture of the expected images,
+// with the alpha premultiplied as in the images.
+func loadTestTexture(t *testing.T) *Texture {
+	texture, err := NewTexture(loadImage(t, "gopher.png"), TextureOptions{PremultipliedAlpha: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return texture
+}
+
+// differingPixels returns the fraction of the pixels of the images
+// with a color component differing by more than pixelTolerance.
+func differingPixels(expected, actual image.Image) float64 {
+	b := expected.Bounds()
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			er, eg, eb, _ := expected.At(x, y).RGBA()
+			ar, ag, ab, _ := actual.At(x, y).RGBA()
+			if differ(er, ar) || differ(eg, ag) || differ(eb, ab) {
+				n++
+			}
+		}
+	}
+	return float64(n) / float64(b.Dx()*b.Dy())
+}
+
+func differ(a, b uint32) bool {
+	d := int(a>>8) - int(b>>8)
+	return d > pixelTolerance || d < -pixelTolerance
+}
+
+func TestSoftwareRenderer(t *testing.T) {
+	newBox := func(width, height float32) *Box {
+		return NewBox(0, width, height)
+	}
+	texCoords := []float32{0, 0, 1, 0, 0, 1, 1, 1}
+	newGroup := func(angle float32) (*Group, *Box) {
+		group1 := NewGroup()
+		b1 := newBox(20, 20)
+		b1.MoveTo(30, 40)
+		b2 := newBox(50, 50)
+		b2.MoveTo(45, -25)
+		b2.Rotate(angle)
+		group1.Append(b1)
+		group1.Append(b2)
+		group := NewGroup()
+		group.Append(group1)
+		b3 := newBox(100, 100)
+		group.Append(b3)
+		return group, b3
+	}
+
+	for _, test := range []struct {
+		filename string
+		shape    func() Shape
+
+		// Largest fraction of differing pixels
+		maxDiff float64
+	}{
+		{"expected_box.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			return box
+		}, 0.002},
+		{"expected_box_rotated_20.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			box.Rotate(20)
+			return box
+		}, 0.004},
+		{"expected_box_translated_10_10.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(111, 0)
+			return box
+		}, 0.002},
+		{"expected_box_yellow.png", func() Shape {
+			box := newBox(100, 100)
+			box.SetColor(color.RGBA{255, 255, 0, 255})
+			box.MoveTo(testWidth/2, 0)
+			return box
+		}, 0.002},
+		{"expected_box_scaled.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			box.Scale(1.5, 1.5)
+			return box
+		}, 0.002},
+		{"expected_line.png", func() Shape {
+			segment := NewSegment(0, 81.5, -40, 238.5, 44)
+			segment.SetColor(color.RGBA{255, 0, 0, 255})
+			return segment
+		}, 0.004},
+		{"expected_box_textured.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			box.SetTexture(loadTestTexture(t), texCoords)
+			return box
+		}, 0.008},
+		{"expected_box_textured_rotated_20.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			box.SetTexture(loadTestTexture(t), texCoords)
+			box.Rotate(20)
+			return box
+		}, 0.008},
+		{"expected_box_partial_texture_rotated_20.png", func() Shape {
+			box := newBox(100, 100)
+			box.MoveTo(testWidth/2, 0)
+			box.SetTexture(loadTestTexture(t), []float32{0, 0, 0.5, 0, 0, 0.5, 0.5, 0.5})
+			box.Rotate(20)
+			return box
+		}, 0.008},
+		{"expected_group.png", func() Shape {
+			group, b3 := newGroup(20)
+			b3.MoveTo(testWidth/2, 0)
+			return group
+		}, 0.004},
+		{"expected_group_translated_20_15.png", func() Shape {
+			group, b3 := newGroup(10)
+			b3.MoveTo(testWidth/2, 0)
+			cx, cy := group.Center()
+			group.MoveTo(cx+20, cy+15)
+			return group
+		}, 0.004},
+		{"expected_group_rotated_25.png", func() Shape {
+			group, b3 := newGroup(10)
+			b3.MoveTo(testWidth/2, 0)
+			group.Rotate(25)
+			return group
+		}, 0.004},
+	} {
+		renderer := NewSoftwareRenderer(testWidth, testHeight)
+		renderer.Clear(color.Black)
+		shape := test.shape()
+		shape.SetRenderer(renderer)
+		shape.AttachToWorld(testWorld{})
+		shape.Draw()
+		if diff := differingPixels(loadImage(t, test.filename), renderer.Image()); diff > test.maxDiff {
+			t.Errorf("%s: %.2f%% of the pixels differ", test.filename, diff*100)
+		}
+	}
+}
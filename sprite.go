@@ -0,0 +1,248 @@
+package shapes
+
+import (
+	"time"
+
+	"github.com/remogatto/shaders"
+)
+
+// AnimationMode is the order in which a sprite plays its frames.
+type AnimationMode int
+
+const (
+	// LoopMode plays the frames from the first to the last, then
+	// starts again from the first.
+	LoopMode AnimationMode = iota
+
+	// PingPongMode plays the frames forward and backward.
+	PingPongMode
+
+	// OnceMode plays the frames once and stops on the last one.
+	OnceMode
+)
+
+// Frame is a texture region shown for the given duration.
+type Frame struct {
+	Region   *TextureRegion
+	Duration time.Duration
+}
+
+// NewFrames returns frames showing the regions for the same
+// duration.
+func NewFrames(duration time.Duration, regions ...*TextureRegion) []Frame {
+	frames := make([]Frame, len(regions))
+	for i, r := range regions {
+		frames[i] = Frame{r, duration}
+	}
+	return frames
+}
+
+// Sprite is a box showing a sequence of texture regions. The
+// animation is advanced explicitly by Update, usually once per
+// rendered frame:
+//
+//	walk := shapes.NewSprite(program, 32, 32, shapes.NewFrames(100*time.Millisecond,
+//		atlas.Region("walk1"), atlas.Region("walk2"), atlas.Region("walk3")))
+//	walk.Play()
+//	...
+//	walk.Update(dt)
+//	walk.Draw()
+type Sprite struct {
+	Box
+
+	frames []Frame
+	mode   AnimationMode
+
+	// Index of the shown frame
+	current int
+
+	// Time elapsed since the current frame has been shown
+	elapsed time.Duration
+
+	// Direction of the ping-pong animation, 1 forward, -1
+	// backward
+	direction int
+
+	playing bool
+
+	// True when a OnceMode animation has shown its last frame to
+	// the end
+	finished bool
+
+	// Callbacks
+	onLoop     func()
+	onComplete func()
+}
+
+// NewSprite creates a new sprite of the given size playing frames
+// in LoopMode. The sprite shows the first frame and it's paused.
+func NewSprite(program shaders.Program, width, height float32, frames []Frame) *Sprite {
+	sprite := &Sprite{Box: *NewBox(program, width, height)}
+	sprite.SetFrames(frames)
+	return sprite
+}
+
+// Frames returns the frames of the sprite.
+func (s *Sprite) Frames() []Frame {
+	return s.frames
+}
+
+// SetFrames replaces the frames of the sprite and shows the first
+// one.
+func (s *Sprite) SetFrames(frames []Frame) {
+	s.frames = frames
+	s.Seek(0)
+}
+
+// Mode returns the animation mode of the sprite.
+func (s *Sprite) Mode() AnimationMode {
+	return s.mode
+}
+
+// SetMode sets the animation mode of the sprite.
+func (s *Sprite) SetMode(mode AnimationMode) {
+	s.mode = mode
+}
+
+// Play starts or resumes the animation. A sprite which completed its
+// animation in OnceMode starts again from the first frame, a paused
+// one resumes from the frame it was showing.
+func (s *Sprite) Play() {
+	if s.finished {
+		s.Reset()
+	}
+	s.playing = true
+}
+
+// Pause pauses the animation on the current frame.
+func (s *Sprite) Pause() {
+	s.playing = false
+}
+
+// Playing returns true if the animation is playing.
+func (s *Sprite) Playing() bool {
+	return s.playing
+}
+
+// Frame returns the index of the frame shown by the sprite.
+func (s *Sprite) Frame() int {
+	return s.current
+}
+
+// Reset shows the first frame from its beginning. The sprite keeps
+// playing or stays paused.
+func (s *Sprite) Reset() {
+	s.Seek(0)
+}
+
+// Seek shows the frame with the given index from its beginning.
+// Out of range indices are clamped.
+func (s *Sprite) Seek(frame int) {
+	if frame >= len(s.frames) {
+		frame = len(s.frames) - 1
+	}
+	if frame < 0 {
+		frame = 0
+	}
+	s.current, s.elapsed, s.direction = frame, 0, 1
+	s.finished = false
+	s.showFrame()
+}
+
+// OnLoop sets a function called each time a LoopMode or PingPongMode
+// animation starts again from the first frame.
+func (s *Sprite) OnLoop(fn func()) {
+	s.onLoop = fn
+}
+
+// OnComplete sets a function called when a OnceMode animation shows
+// its last frame to the end.
+func (s *Sprite) OnComplete(fn func()) {
+	s.onComplete = fn
+}
+
+// Update advances the animation by dt, skipping frames if dt is
+// longer than the current frame. Frames with no duration are shown
+// for one update.
+func (s *Sprite) Update(dt time.Duration) {
+	if !s.playing || len(s.frames) == 0 {
+		return
+	}
+	s.elapsed += dt
+	for s.playing {
+		d := s.frames[s.current].Duration
+		if s.elapsed < d {
+			break
+		}
+		s.elapsed -= d
+		s.advance()
+		if d <= 0 {
+			break
+		}
+	}
+}
+
+// advance shows the next frame according to the mode.
+func (s *Sprite) advance() {
+	last := len(s.frames) - 1
+	switch s.mode {
+	case LoopMode:
+		if s.current < last {
+			s.current++
+		} else {
+			s.current = 0
+			s.callback(s.onLoop)
+		}
+	case PingPongMode:
+		next := s.current + s.direction
+		if next < 0 || next > last {
+			s.direction = -s.direction
+			next = s.current + s.direction
+			if next < 0 || next > last {
+				next = s.current
+			}
+		}
+		s.current = next
+		if (s.current == 0 && s.direction < 0) || last == 0 {
+			s.direction = 1
+			s.callback(s.onLoop)
+		}
+	case OnceMode:
+		if s.current < last {
+			s.current++
+			break
+		}
+		s.playing, s.finished = false, true
+		s.elapsed = 0
+		s.callback(s.onComplete)
+		return
+	}
+	s.showFrame()
+}
+
+// callback calls fn if it's not nil.
+func (s *Sprite) callback(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
+
+// showFrame maps the region of the current frame on the box.
+func (s *Sprite) showFrame() {
+	if len(s.frames) == 0 || s.frames[s.current].Region == nil {
+		return
+	}
+	s.SetTextureRegion(s.frames[s.current].Region)
+}
+
+// Clone returns a deep copy of the sprite. The frames share their
+// regions and the callbacks are not copied.
+func (s *Sprite) Clone() Shape {
+	c := new(Sprite)
+	c.width, c.height = s.width, s.height
+	s.cloneInto(&c.Base)
+	c.frames = append([]Frame(nil), s.frames...)
+	c.mode, c.current, c.elapsed, c.direction = s.mode, s.current, s.elapsed, s.direction
+	c.playing, c.finished = s.playing, s.finished
+	return c
+}
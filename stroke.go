@@ -0,0 +1,284 @@
+package shapes
+
+import "math"
+
+// LineJoin is the shape used to join two segments of a stroked line.
+type LineJoin int
+
+const (
+	// MiterJoin extends the outer edges of the segments until
+	// they meet. It falls back to BevelJoin when the miter is
+	// longer than MiterLimit times the half width.
+	MiterJoin LineJoin = iota
+
+	// BevelJoin connects the outer corners of the segments with
+	// a straight edge.
+	BevelJoin
+
+	// RoundJoin connects the outer corners of the segments with
+	// an arc.
+	RoundJoin
+)
+
+// LineCap is the shape used at the ends of an open stroked line.
+type LineCap int
+
+const (
+	// ButtCap ends the line exactly at its end points.
+	ButtCap LineCap = iota
+
+	// SquareCap extends the line by half its width.
+	SquareCap
+
+	// RoundCap ends the line with a half circle.
+	RoundCap
+)
+
+// MiterLimit is the maximum ratio between the length of a miter and
+// the half width of the line.
+var MiterLimit float32 = 4
+
+// stroke returns the vertices of the triangles covering the line
+// through the given points, stroked with the given width, join and
+// cap. If closed is true the last point is joined with the first
+// one and caps are not drawn. Adjacent triangles don't overlap, so
+// translucent lines are blended once, except at turns too sharp for
+// the length of the segments.
+func stroke(points []float32, width float32, join LineJoin, cap LineCap, closed bool) []float32 {
+	// Drop repeated points, they have no direction
+	var pts []float32
+	for i := 0; i+1 < len(points); i += 2 {
+		n := len(pts)
+		if n >= 2 && pts[n-2] == points[i] && pts[n-1] == points[i+1] {
+			continue
+		}
+		pts = append(pts, points[i], points[i+1])
+	}
+	n := len(pts) / 2
+	if closed && n > 2 && pts[0] == pts[n*2-2] && pts[1] == pts[n*2-1] {
+		n--
+		pts = pts[:n*2]
+	}
+	if n < 2 || width <= 0 {
+		return nil
+	}
+	if n < 3 {
+		closed = false
+	}
+
+	s := &stroker{hw: width / 2}
+
+	// Inner corners of the joins, where the segments are trimmed
+	// so that they don't overlap
+	first, last := 1, n-1
+	if closed {
+		first, last = 0, n
+	}
+	corners := make([]corner, n)
+	for i := first; i < last; i++ {
+		prev, next := (i+n-1)%n, (i+1)%n
+		corners[i] = s.innerCorner(
+			pts[prev*2], pts[prev*2+1],
+			pts[i*2], pts[i*2+1],
+			pts[next*2], pts[next*2+1],
+		)
+	}
+
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+	for i := 0; i < segments; i++ {
+		j := (i + 1) % n
+		s.segment(pts[i*2], pts[i*2+1], pts[j*2], pts[j*2+1], corners[i], corners[j])
+	}
+
+	for i := first; i < last; i++ {
+		prev, next := (i+n-1)%n, (i+1)%n
+		s.join(join,
+			pts[prev*2], pts[prev*2+1],
+			pts[i*2], pts[i*2+1],
+			pts[next*2], pts[next*2+1],
+			corners[i],
+		)
+	}
+
+	if !closed {
+		s.cap(cap, pts[0], pts[1], pts[2], pts[3])
+		s.cap(cap, pts[n*2-2], pts[n*2-1], pts[n*2-4], pts[n*2-3])
+	}
+
+	return s.vertices
+}
+
+// stroker accumulates the triangles of a stroked line.
+type stroker struct {
+	// Half width of the line
+	hw float32
+
+	vertices []float32
+}
+
+func (s *stroker) triangle(ax, ay, bx, by, cx, cy float32) {
+	s.vertices = append(s.vertices, ax, ay, bx, by, cx, cy)
+}
+
+// normal returns the left normal of the segment (a, b) scaled to the
+// half width.
+func (s *stroker) normal(ax, ay, bx, by float32) (float32, float32) {
+	dx, dy := bx-ax, by-ay
+	l := float32(math.Hypot(float64(dx), float64(dy)))
+	return -dy / l * s.hw, dx / l * s.hw
+}
+
+// corner is the inner corner of a join, where the inner edges of the
+// segments meet.
+type corner struct {
+	x, y float32
+
+	// left is true if the corner lies on the left of the line
+	left bool
+
+	// ok is false if the segments are not trimmed at the join,
+	// e.g. at the ends of the line or when the segments are too
+	// short for the corner
+	ok bool
+}
+
+// innerCorner returns the inner corner of the join in b between the
+// segments (a, b) and (b, c). Segments are trimmed at most halfway, so
+// that each segment can be trimmed at both ends.
+func (s *stroker) innerCorner(ax, ay, bx, by, cx, cy float32) corner {
+	n0x, n0y := s.normal(ax, ay, bx, by)
+	n1x, n1y := s.normal(bx, by, cx, cy)
+	mx, my := n0x+n1x, n0y+n1y
+	ml2 := mx*mx + my*my
+	if ml2 == 0 {
+		// The line turns back
+		return corner{}
+	}
+	// The inner edges are offset by the half width, they meet
+	// along the bisector of the normals
+	k := 2 * s.hw * s.hw / ml2
+	left := n0x*n1y-n0y*n1x > 0
+	if !left {
+		k = -k
+	}
+	dx, dy := mx*k, my*k
+
+	// Distances from b along the segments, scaled by their length
+	l0 := (ax-bx)*(ax-bx) + (ay-by)*(ay-by)
+	l1 := (cx-bx)*(cx-bx) + (cy-by)*(cy-by)
+	if dx*(ax-bx)+dy*(ay-by) > l0/2 || dx*(cx-bx)+dy*(cy-by) > l1/2 {
+		return corner{}
+	}
+	return corner{bx + dx, by + dy, left, true}
+}
+
+// segment adds the quad covering the segment (a, b), trimmed at the
+// inner corners of the joins in a and b.
+func (s *stroker) segment(ax, ay, bx, by float32, ca, cb corner) {
+	nx, ny := s.normal(ax, ay, bx, by)
+	alx, aly, arx, ary := s.ends(ax, ay, nx, ny, ca)
+	blx, bly, brx, bry := s.ends(bx, by, nx, ny, cb)
+	s.triangle(alx, aly, arx, ary, blx, bly)
+	s.triangle(blx, bly, arx, ary, brx, bry)
+}
+
+// ends returns the left and right corners of a segment in p, given
+// its left normal and the inner corner of the join in p.
+func (s *stroker) ends(px, py, nx, ny float32, c corner) (lx, ly, rx, ry float32) {
+	lx, ly, rx, ry = px+nx, py+ny, px-nx, py-ny
+	if c.ok && c.left {
+		lx, ly = c.x, c.y
+	} else if c.ok {
+		rx, ry = c.x, c.y
+	}
+	return lx, ly, rx, ry
+}
+
+// join fills the gap between the segments (a, b) and (b, c) around
+// the corner in b: the inner part, left by the trimmed segments, and
+// the outer part.
+func (s *stroker) join(join LineJoin, ax, ay, bx, by, cx, cy float32, c corner) {
+	n0x, n0y := s.normal(ax, ay, bx, by)
+	n1x, n1y := s.normal(bx, by, cx, cy)
+	turn := n0x*n1y - n0y*n1x
+	if turn == 0 && n0x*n1x+n0y*n1y > 0 {
+		// Collinear segments
+		return
+	}
+
+	// The gap lies on the right side for left turns
+	if turn > 0 {
+		n0x, n0y, n1x, n1y = -n0x, -n0y, -n1x, -n1y
+	}
+
+	if c.ok {
+		s.triangle(c.x, c.y, bx+n0x, by+n0y, bx, by)
+		s.triangle(c.x, c.y, bx, by, bx+n1x, by+n1y)
+	}
+
+	switch join {
+	case MiterJoin:
+		mx, my := n0x+n1x, n0y+n1y
+		ml := float32(math.Hypot(float64(mx), float64(my)))
+		if ml > 0 {
+			// Cosine of half the angle between the normals
+			cos := ml / (2 * s.hw)
+			if 1/cos <= MiterLimit {
+				k := s.hw / cos / ml
+				mx, my = bx+mx*k, by+my*k
+				s.triangle(bx, by, bx+n0x, by+n0y, mx, my)
+				s.triangle(bx, by, mx, my, bx+n1x, by+n1y)
+				return
+			}
+		}
+		s.triangle(bx, by, bx+n0x, by+n0y, bx+n1x, by+n1y)
+	case BevelJoin:
+		s.triangle(bx, by, bx+n0x, by+n0y, bx+n1x, by+n1y)
+	case RoundJoin:
+		a0 := math.Atan2(float64(n0y), float64(n0x))
+		a1 := math.Atan2(float64(n1y), float64(n1x))
+		// Normals rotate like the segments, take the short way
+		sweep := a1 - a0
+		if sweep > math.Pi {
+			sweep -= 2 * math.Pi
+		} else if sweep < -math.Pi {
+			sweep += 2 * math.Pi
+		}
+		s.arc(bx, by, a0, sweep)
+	}
+}
+
+// cap adds the cap of the line ending in a, the line coming from b.
+func (s *stroker) cap(cap LineCap, ax, ay, bx, by float32) {
+	nx, ny := s.normal(bx, by, ax, ay)
+	switch cap {
+	case SquareCap:
+		// Direction of the line scaled to the half width
+		dx, dy := ny, -nx
+		s.triangle(ax+nx, ay+ny, ax-nx, ay-ny, ax+nx+dx, ay+ny+dy)
+		s.triangle(ax+nx+dx, ay+ny+dy, ax-nx, ay-ny, ax-nx+dx, ay-ny+dy)
+	case RoundCap:
+		a0 := math.Atan2(float64(ny), float64(nx))
+		s.arc(ax, ay, a0, -math.Pi)
+	}
+}
+
+// arc adds a fan of triangles centered in c covering the arc of
+// radius hw starting at angle a0 and spanning sweep radians.
+func (s *stroker) arc(cx, cy float32, a0, sweep float64) {
+	steps := int(math.Ceil(float64(segmentsForRadius(s.hw)) * math.Abs(sweep) / (2 * math.Pi)))
+	if steps < 1 {
+		steps = 1
+	}
+	r := float64(s.hw)
+	px, py := cx+float32(r*math.Cos(a0)), cy+float32(r*math.Sin(a0))
+	for i := 1; i <= steps; i++ {
+		a := a0 + sweep*float64(i)/float64(steps)
+		x, y := cx+float32(r*math.Cos(a)), cy+float32(r*math.Sin(a))
+		s.triangle(cx, cy, px, py, x, y)
+		px, py = x, y
+	}
+}
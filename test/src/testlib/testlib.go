@@ -218,24 +218,19 @@ func (w *world) View() mathgl.Mat4f {
 	return w.viewMatrix
 }
 
-func (w *world) addImageAsTexture(filename string) uint32 {
-	var texBuffer uint32
+// loadTexture loads a texture with the given filename from the
+// resource folder. The alpha is premultiplied as in the expected
+// images.
+func loadTexture(filename string) *shapes.Texture {
 	texImg, err := loadImageResource(filename)
 	if err != nil {
 		panic(err)
 	}
-	b := texImg.Bounds()
-	rgbaImage := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
-	draw.Draw(rgbaImage, rgbaImage.Bounds(), texImg, b.Min, draw.Src)
-
-	width, height := gl.Sizei(b.Dx()), gl.Sizei(b.Dy())
-	gl.GenTextures(1, &texBuffer)
-	gl.BindTexture(gl.TEXTURE_2D, texBuffer)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Void(&rgbaImage.Pix[0]))
-
-	return texBuffer
+	texture, err := shapes.NewTexture(texImg, shapes.TextureOptions{PremultipliedAlpha: true})
+	if err != nil {
+		panic(err)
+	}
+	return texture
 }
 
 // loadImageResource loads an image with the given filename from the
@@ -1,12 +1,23 @@
 package testlib
 
 import (
+	"bytes"
 	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"time"
 
 	"github.com/aded/shapes"
+	"github.com/aded/shapes/collision"
+	"github.com/aded/shapes/tween"
+	"github.com/aded/shapes/viewport"
 	"github.com/remogatto/imagetest"
+	"github.com/remogatto/mandala"
 	"github.com/remogatto/mandala/test/src/testlib"
+	"github.com/remogatto/mathgl"
 	gl "github.com/remogatto/opengles2"
 )
 
@@ -65,9 +76,79 @@ func (t *TestSuite) TestShape() {
 	angle := box.Angle()
 	t.Equal(float32(10), angle)
 
-	// String representation
+	// String representation, the bounds contain the rotated box
 
-	t.Equal("(5,10)-(15,30)", box.String())
+	t.Equal("(3,9)-(17,31)", box.String())
+}
+
+func (t *TestSuite) TestBounds() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 20)
+	box.MoveTo(10, 20)
+	t.Equal(shapes.Rect{MinX: 5, MinY: 10, MaxX: 15, MaxY: 30}, box.AABB())
+
+	// Rotated bounds
+	box.Rotate(90)
+	r := box.AABB()
+	t.True(r.MinX > -0.01 && r.MinX < 0.01, r.String())
+	t.True(r.MaxX > 19.99 && r.MaxX < 20.01, r.String())
+	t.True(r.MinY > 14.99 && r.MinY < 15.01, r.String())
+	t.True(r.MaxY > 24.99 && r.MaxY < 25.01, r.String())
+
+	// The oriented box keeps the size of the shape
+	obb := box.OBB()
+	t.Equal(shapes.OrientedBox{X: 10, Y: 20, HalfWidth: 5, HalfHeight: 10, Angle: 90}, obb)
+
+	// Scaled bounds
+	box.Rotate(-90)
+	box.Scale(2, 0.5)
+	t.Equal(shapes.Rect{MinX: 0, MinY: 15, MaxX: 20, MaxY: 25}, box.AABB())
+
+	// Groups union the transformed bounds of their shapes
+	group := shapes.NewGroup()
+	group.Append(box)
+	group.Append(shapes.NewBox(t.renderState.boxProgram, 10, 10))
+	t.Equal(shapes.Rect{MinX: -5, MinY: -5, MaxX: 20, MaxY: 25}, group.AABB())
+	t.Equal("(-5,-5)-(20,25)", group.Bounds().String())
+}
+
+func (t *TestSuite) TestTransform() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 20)
+
+	// Transformations compose instead of overwriting each other
+	box.Rotate(30)
+	box.Scale(2, 2)
+	box.MoveTo(100, 50)
+	tr := box.Transform()
+	t.Equal(float32(30), tr.Angle)
+	t.Equal(float32(2), tr.ScaleX)
+	t.Equal(float32(2), tr.ScaleY)
+	t.Equal(float32(100), tr.X)
+	t.Equal(float32(50), tr.Y)
+
+	box.Scale(1.5, 0.5)
+	tr = box.Transform()
+	t.Equal(float32(3), tr.ScaleX)
+	t.Equal(float32(1), tr.ScaleY)
+	t.Equal(float32(30), box.Angle())
+
+	// Rotating around a point moves the center
+	box.SetTransform(shapes.NewTransform())
+	box.RotateAround(10, 0, 180)
+	x, y := box.Center()
+	t.True(x > 19.99 && x < 20.01)
+	t.True(y > -0.01 && y < 0.01)
+	t.Equal(float32(180), box.Angle())
+
+	// Changing the pivot doesn't move the shape
+	box.SetTransform(shapes.NewTransform())
+	box.MoveTo(40, 40)
+	box.SetPivot(5, 10)
+	x, y = box.Center()
+	t.Equal(float32(40), x)
+	t.Equal(float32(40), y)
+	tr = box.Transform()
+	t.Equal(float32(45), tr.X)
+	t.Equal(float32(50), tr.Y)
 }
 
 func (t *TestSuite) TestBox() {
@@ -247,7 +328,7 @@ func (t *TestSuite) TestTexturedBox() {
 		box.MoveTo(float32(w/2), 0)
 
 		// Add an image as a texture
-		gopherTexture := world.addImageAsTexture(texFilename)
+		gopherTexture := loadTexture(texFilename)
 
 		texCoords := []float32{
 			0, 0,
@@ -284,7 +365,7 @@ func (t *TestSuite) TestTexturedRotatedBox() {
 		box.MoveTo(float32(w/2), 0)
 
 		// Add an image as a texture
-		gopherTexture := world.addImageAsTexture(texFilename)
+		gopherTexture := loadTexture(texFilename)
 		texCoords := []float32{
 			0, 0,
 			1, 0,
@@ -322,7 +403,7 @@ func (t *TestSuite) TestPartialTextureRotatedBox() {
 		box.MoveTo(float32(w/2), 0)
 
 		// Add an image as a texture
-		gopherTexture := world.addImageAsTexture(texFilename)
+		gopherTexture := loadTexture(texFilename)
 
 		texCoords := []float32{
 			0, 0,
@@ -479,6 +560,1629 @@ func (t *TestSuite) TestGroupRotated() {
 	}
 }
 
+func (t *TestSuite) TestSoftwareBox() {
+	filename := "expected_box.png"
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+	renderer := shapes.NewSoftwareRenderer(w, h)
+	renderer.Clear(color.Black)
+	box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	box.SetRenderer(renderer)
+	box.AttachToWorld(world)
+	box.MoveTo(float32(w/2), 0)
+	box.Draw()
+	distance, exp, act, err := testlib.TestImage(filename, renderer.Image(), imagetest.Center)
+	if err != nil {
+		panic(err)
+	}
+	t.True(distance < distanceThreshold, distanceError(distance, filename))
+	if t.Failed() {
+		saveExpAct(t.outputPath, "failed_software_"+filename, exp, act)
+	}
+}
+
+func (t *TestSuite) TestSoftwareTexturedRotatedBox() {
+	filename := "expected_box_textured_rotated_20.png"
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+	renderer := shapes.NewSoftwareRenderer(w, h)
+	renderer.Clear(color.Black)
+	box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	box.SetRenderer(renderer)
+	box.AttachToWorld(world)
+	box.MoveTo(float32(w/2), 0)
+
+	// Textures keep their pixels for the software renderer
+	texCoords := []float32{
+		0, 0,
+		1, 0,
+		0, 1,
+		1, 1,
+	}
+	box.SetTexture(loadTexture(texFilename), texCoords)
+
+	box.Rotate(20.0)
+	box.Draw()
+	distance, exp, act, err := testlib.TestImage(filename, renderer.Image(), imagetest.Center)
+	if err != nil {
+		panic(err)
+	}
+	t.True(distance < texDistThreshold, distanceError(distance, filename))
+	if t.Failed() {
+		saveExpAct(t.outputPath, "failed_software_"+filename, exp, act)
+	}
+}
+
+func (t *TestSuite) TestCircle() {
+	circle := shapes.NewCircle(t.renderState.boxProgram, 50)
+	t.Equal(float32(50), circle.Radius())
+
+	size := circle.Bounds().Size()
+	t.Equal(100, size.X)
+	t.Equal(100, size.Y)
+
+	// Automatic tessellation, the fan contains the center and
+	// closes on the first point of the outline
+	n := circle.Segments()
+	t.True(n >= 8)
+	t.Equal((n+2)*2, len(circle.Vertices()))
+
+	// Explicit tessellation
+	circle.SetSegments(6)
+	t.Equal(6, circle.Segments())
+	t.Equal(16, len(circle.Vertices()))
+
+	clone := circle.Clone().(*shapes.Circle)
+	t.Equal(6, clone.Segments())
+	t.Equal(float32(50), clone.Radius())
+
+	// Zooming the world adds segments
+	circle.SetSegments(0)
+	camera := shapes.NewCamera2D(320, 480)
+	circle.AttachToWorld(camera)
+	t.Equal(n, circle.Segments())
+	camera.SetZoom(4)
+	t.True(circle.Segments() > n)
+	v := viewport.New(160, 240, viewport.Fit)
+	v.Resize(640, 960)
+	circle.AttachToWorld(v)
+	t.True(circle.Segments() > n)
+	circle.SetRenderer(shapes.NewSoftwareRenderer(320, 480))
+	circle.Draw()
+	t.Equal((circle.Segments()+2)*2, len(circle.Vertices()))
+}
+
+func (t *TestSuite) TestEllipse() {
+	ellipse := shapes.NewEllipse(t.renderState.boxProgram, 40, 20)
+	rx, ry := ellipse.Radii()
+	t.Equal(float32(40), rx)
+	t.Equal(float32(20), ry)
+
+	size := ellipse.Bounds().Size()
+	t.Equal(80, size.X)
+	t.Equal(40, size.Y)
+
+	x, y := ellipse.Center()
+	t.Equal(float32(0), x)
+	t.Equal(float32(0), y)
+}
+
+func (t *TestSuite) TestPolygon() {
+	// A rectangle
+	polygon := shapes.NewPolygon(t.renderState.boxProgram, []float32{
+		0, 0,
+		20, 0,
+		20, 10,
+		0, 10,
+	})
+
+	x, y := polygon.Center()
+	t.Equal(float32(10), x)
+	t.Equal(float32(5), y)
+	t.Equal("(0,0)-(20,10)", polygon.String())
+
+	// Two triangles
+	t.Equal(12, len(polygon.Vertices()))
+
+	// A concave, clockwise, L-shaped outline
+	polygon = shapes.NewPolygon(t.renderState.boxProgram, []float32{
+		0, 0,
+		0, 20,
+		10, 20,
+		10, 10,
+		20, 10,
+		20, 0,
+	})
+	t.Equal(4*3*2, len(polygon.Vertices()))
+	size := polygon.Bounds().Size()
+	t.Equal(20, size.X)
+	t.Equal(20, size.Y)
+
+	// Polygons can be grouped like any other shape
+	group := shapes.NewGroup()
+	group.Append(polygon)
+	t.Equal(polygon.Bounds(), group.Bounds())
+}
+
+func (t *TestSuite) TestPolyline() {
+	polyline := shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 0, 100, 0}, 10)
+	t.Equal(float32(10), polyline.Width())
+
+	x, y := polyline.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(0), y)
+
+	// A single quad
+	t.Equal(2*3*2, len(polyline.Vertices()))
+
+	// Square caps add a quad at each end
+	polyline.SetCap(shapes.SquareCap)
+	t.Equal(6*3*2, len(polyline.Vertices()))
+
+	// A corner is filled by the join, the segments are trimmed at
+	// the inner corner
+	polyline = shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 0, 100, 0, 100, 100}, 10)
+	polyline.SetJoin(shapes.BevelJoin)
+	t.Equal(7*3*2, len(polyline.Vertices()))
+	polyline.SetJoin(shapes.MiterJoin)
+	t.Equal(8*3*2, len(polyline.Vertices()))
+
+	// Translucent lines are blended once at the joins
+	world := newWorld(320, 480)
+	renderer := shapes.NewSoftwareRenderer(320, 480)
+	for _, join := range []shapes.LineJoin{shapes.MiterJoin, shapes.BevelJoin, shapes.RoundJoin} {
+		renderer.Clear(color.Black)
+		polyline = shapes.NewPolyline(t.renderState.segmentProgram, []float32{100, -50, 200, -50, 200, 50, 120, 60}, 20)
+		polyline.SetJoin(join)
+		polyline.SetColor(color.White)
+		polyline.SetOpacity(0.5)
+		polyline.SetRenderer(renderer)
+		polyline.AttachToWorld(world)
+		polyline.Draw()
+		img := renderer.Image()
+		overlapping := 0
+		for y := 0; y < 480; y++ {
+			for x := 0; x < 320; x++ {
+				if img.RGBAAt(x, y).R > 140 {
+					overlapping++
+				}
+			}
+		}
+		t.Equal(0, overlapping, fmt.Sprint(join))
+		t.Equal(uint8(128), img.RGBAAt(150, 240+50).R)
+	}
+}
+
+func (t *TestSuite) TestSegmentWidth() {
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 10, 15, 20, 20)
+	t.Equal(float32(0), segment.Width())
+	t.Equal(4, len(segment.Vertices()))
+
+	// Wide segments are stroked as triangles
+	segment.SetWidth(4)
+	t.Equal(float32(4), segment.Width())
+	t.Equal(2*3*2, len(segment.Vertices()))
+
+	segment.SetWidth(0)
+	t.Equal(4, len(segment.Vertices()))
+}
+
+func (t *TestSuite) TestContains() {
+	box := shapes.NewBox(t.renderState.boxProgram, 100, 10)
+	box.MoveTo(50, 50)
+	t.True(box.Contains(50, 50))
+	t.True(box.Contains(95, 52))
+	t.False(box.Contains(50, 60))
+
+	// Points in the bounds but outside the rotated box
+	box.Rotate(45)
+	t.True(box.Contains(80, 80))
+	t.False(box.Contains(85, 50))
+	t.True(box.Bounds().Min.X < 85)
+
+	// Segments contain the points within the tolerance
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 0, 0, 100, 100)
+	t.True(segment.Contains(50, 52))
+	t.False(segment.Contains(50, 60))
+	t.False(segment.Contains(110, 110))
+	segment.SetTolerance(10)
+	t.True(segment.Contains(50, 60))
+
+	// Wide segments contain their stroke
+	segment.SetTolerance(0)
+	segment.SetWidth(30)
+	t.True(segment.Contains(50, 60))
+}
+
+func (t *TestSuite) TestShapeAt() {
+	back := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	front := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	front.MoveTo(40, 40)
+
+	group := shapes.NewGroup()
+	group.Append(back)
+	group.Append(front)
+
+	t.True(group.ShapeAt(40, 40) == front)
+	t.True(group.ShapeAt(0, 0) == back)
+	t.Nil(group.ShapeAt(200, 200))
+	t.True(group.Contains(0, 0))
+	t.False(group.Contains(200, 200))
+
+	// Touch coordinates have the origin in the top-left corner
+	world := newWorld(320, 480)
+	x, y := shapes.ScreenToWorld(world, 160, 120, 320, 480)
+	t.True(x > 159.99 && x < 160.01)
+	t.True(y > 119.99 && y < 120.01)
+	x, y = shapes.WorldToScreen(world, x, y, 320, 480)
+	t.True(x > 159.99 && x < 160.01)
+	t.True(y > 119.99 && y < 120.01)
+}
+
+func (t *TestSuite) TestCollision() {
+	a := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b.MoveTo(15, 2)
+
+	// The boxes overlap by 5 along x
+	c, ok := collision.Collide(a, b)
+	t.True(ok)
+	t.Equal(float32(5), c.Depth)
+	t.Equal(mathgl.Vec2f{1, 0}, c.Normal)
+	t.Equal(mathgl.Vec2f{-5, 0}, c.MTV())
+
+	// The contact points are the corners of the overlapping region
+	t.Equal(4, len(c.Points))
+
+	// Moving by the MTV separates the boxes
+	mtv := c.MTV()
+	a.Move(mtv[0]-0.1, mtv[1])
+	t.False(collision.Overlaps(a, b))
+
+	// Rotated boxes are tested exactly: their bounds overlap but
+	// the boxes don't
+	a.MoveTo(0, 0)
+	a.Rotate(45)
+	b.MoveTo(22, 22)
+	t.True(a.AABB().Overlaps(b.AABB()))
+	t.False(collision.Overlaps(a, b))
+
+	// Segments
+	segment := shapes.NewSegment(t.renderState.segmentProgram, -20, -20, 20, 20)
+	c, ok = collision.Collide(segment, b)
+	t.True(ok)
+	t.Equal(2, len(c.Points))
+	segment.Move(0, -10)
+	t.False(collision.Overlaps(segment, b))
+
+	// Groups collide when one of their shapes does
+	group := shapes.NewGroup()
+	group.Append(shapes.NewBox(t.renderState.boxProgram, 4, 4))
+	group.Append(segment)
+	t.False(collision.Overlaps(group, b))
+	group.Move(5, 5)
+	t.True(collision.Overlaps(group, b))
+}
+
+// countingRenderer counts the commands it renders.
+type countingRenderer struct {
+	shapes.Renderer
+	calls int
+}
+
+func (r *countingRenderer) Render(cmd *shapes.DrawCommand) {
+	r.calls++
+	r.Renderer.Render(cmd)
+}
+
+func (t *TestSuite) TestBatchRenderer() {
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+
+	draw := func(renderer shapes.Renderer) {
+		group := shapes.NewGroup()
+		for i := 0; i < 10; i++ {
+			box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+			box.AttachToWorld(world)
+			box.MoveTo(float32(30+i*25), float32(i*10))
+			box.Rotate(float32(i * 10))
+			group.Append(box)
+		}
+		circle := shapes.NewCircle(t.renderState.boxProgram, 30)
+		circle.AttachToWorld(world)
+		circle.MoveTo(float32(w/2), -100)
+		group.Append(circle)
+		segment := shapes.NewSegment(t.renderState.segmentProgram, 10, -200, 300, -150)
+		segment.AttachToWorld(world)
+		group.Append(segment)
+		group.SetRenderer(renderer)
+		group.Draw()
+	}
+
+	expected := shapes.NewSoftwareRenderer(w, h)
+	expected.Clear(color.Black)
+	draw(expected)
+
+	actual := shapes.NewSoftwareRenderer(w, h)
+	actual.Clear(color.Black)
+	counter := &countingRenderer{Renderer: actual}
+	draw(shapes.NewBatchRenderer(counter))
+
+	// Boxes and the circle share the program, the segment has its
+	// own batch
+	t.Equal(2, counter.calls)
+
+	// Transforming on the CPU may move a few edge pixels
+	exp, act := expected.Image(), actual.Image()
+	differ := 0
+	for i := 0; i < len(exp.Pix); i += 4 {
+		if exp.Pix[i] != act.Pix[i] || exp.Pix[i+1] != act.Pix[i+1] || exp.Pix[i+2] != act.Pix[i+2] {
+			differ++
+		}
+	}
+	t.True(differ < 50, fmt.Sprintf("%d pixels differ", differ))
+}
+
+func (t *TestSuite) TestBoxBuffers() {
+	filename := "expected_box_yellow.png"
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+		box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+		box.AttachToWorld(world)
+		box.MoveTo(float32(w/2), 0)
+
+		// Draw with the buffers uploaded by the first draw
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+		box.Draw()
+
+		// Changing the color uploads the color buffer again
+		box.SetColor(color.RGBA{255, 255, 0, 255})
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+
+		// Released buffers are created again
+		box.Release()
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+
+		t.testDraw <- testlib.Screenshot(t.renderState.window)
+		t.renderState.window.SwapBuffers()
+	}
+	distance, exp, act, err := testlib.TestImage(filename, <-t.testDraw, imagetest.Center)
+	if err != nil {
+		panic(err)
+	}
+	t.True(distance < distanceThreshold, distanceError(distance, filename))
+	if t.Failed() {
+		saveExpAct(t.outputPath, "failed_buffers_"+filename, exp, act)
+	}
+}
+
+func (t *TestSuite) TestGroupChildren() {
+	newBox := func(x, y float32) *shapes.Box {
+		box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+		box.MoveTo(x, y)
+		return box
+	}
+	a, b, c := newBox(0, 0), newBox(100, 0), newBox(100, 100)
+
+	group := shapes.NewGroup()
+	group.Append(a)
+	group.Append(c)
+	t.Nil(group.InsertAt(1, b))
+	t.Equal(3, group.Len())
+	t.True(group.GetAt(1) == b)
+	t.Equal(shapes.Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 105}, group.AABB())
+	x, y := group.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(50), y)
+
+	// Out of range positions
+	t.True(group.InsertAt(4, newBox(0, 0)) != nil)
+	t.True(group.RemoveAt(3) != nil)
+
+	// Groups can't contain themselves or their ancestors
+	inner := shapes.NewGroup()
+	t.Nil(group.Append(inner))
+	t.True(group.Append(group) != nil)
+	t.True(inner.Append(group) != nil)
+	t.True(inner.InsertAt(0, group) != nil)
+	t.True(inner.Parent() == group)
+	t.Equal(0, inner.Len())
+	t.Nil(group.Remove(inner))
+
+	// The center and the bounds follow the removed shapes
+	t.Nil(group.Remove(c))
+	t.True(group.Remove(c) != nil)
+	t.Equal(2, group.Len())
+	t.Equal(shapes.Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 5}, group.AABB())
+	x, y = group.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(0), y)
+
+	t.Nil(group.RemoveAt(0))
+	t.True(group.GetAt(0) == b)
+	x, y = group.Center()
+	t.Equal(float32(100), x)
+	t.Equal(float32(0), y)
+
+	// Iteration stops when the callback returns false
+	group.Append(a)
+	group.Append(c)
+	var visited []shapes.Shape
+	group.Each(func(s shapes.Shape) bool {
+		visited = append(visited, s)
+		return s != a
+	})
+	t.Equal(2, len(visited))
+	t.True(visited[0] == b && visited[1] == a)
+
+	group.Clear()
+	t.Equal(0, group.Len())
+	x, y = group.Center()
+	t.Equal(float32(0), x)
+	t.Equal(float32(0), y)
+	t.True(group.AABB().Empty())
+	t.True(group.Nearest(0, 0) == nil)
+
+	// Shapes without points don't extend the bounds
+	group.Append(newBox(100, 100))
+	group.Append(shapes.NewGroup())
+	group.Append(shapes.NewPolyline(t.renderState.boxProgram, []float32{0, 0}, 1))
+	t.Equal(shapes.Rect{MinX: 95, MinY: 95, MaxX: 105, MaxY: 105}, group.AABB())
+	x, y = group.Center()
+	t.Equal(float32(100), x)
+	t.Equal(float32(100), y)
+	group.SetSpatialIndex(50)
+	t.Equal(1, len(group.ShapesIn(shapes.Rect{MinX: -1000, MinY: -1000, MaxX: 1000, MaxY: 1000})))
+	t.True(group.Nearest(0, 0) == group.GetAt(0))
+}
+
+func (t *TestSuite) TestSceneGraph() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	box.MoveTo(10, 0)
+
+	inner := shapes.NewGroup()
+	inner.Append(box)
+	outer := shapes.NewGroup()
+	outer.Append(inner)
+	t.True(box.Parent() == inner)
+	t.True(inner.Parent() == outer)
+
+	// Transforming the groups doesn't change the local transform
+	// of the box, only its world position
+	inner.RotateAround(0, 0, 90)
+	outer.Move(100, 0)
+	tr := box.Transform()
+	t.Equal(float32(10), tr.X)
+	t.Equal(float32(0), tr.Y)
+	x, y := box.Center()
+	t.Equal(float32(10), x)
+	t.Equal(float32(0), y)
+
+	r := box.AABB()
+	t.True(r.MinX > 94.99 && r.MinX < 95.01, r.String())
+	t.True(r.MaxX > 104.99 && r.MaxX < 105.01, r.String())
+	t.True(r.MinY > 4.99 && r.MinY < 5.01, r.String())
+	t.True(r.MaxY > 14.99 && r.MaxY < 15.01, r.String())
+	t.True(box.Contains(100, 10))
+	t.False(box.Contains(10, 0))
+
+	obb := box.OBB()
+	t.Equal(float32(90), obb.Angle)
+
+	// Groups scale around their center
+	outer.Scale(2, 2)
+	t.Equal(float32(1), box.Transform().ScaleX)
+	r = box.AABB()
+	t.True(r.Dx() > 19.99 && r.Dx() < 20.01, r.String())
+	x, y = outer.Center()
+	t.True(x > 99.99 && x < 100.01)
+	t.True(y > 9.99 && y < 10.01)
+
+	// Appending the box to another group moves it out of the
+	// previous one
+	other := shapes.NewGroup()
+	other.Append(box)
+	t.True(box.Parent() == other)
+	t.Equal(0, inner.Len())
+	t.Equal(shapes.Rect{MinX: 5, MinY: -5, MaxX: 15, MaxY: 5}, box.AABB())
+
+	// Removed shapes are placed in the world by their own
+	// transform
+	t.Nil(other.Remove(box))
+	t.True(box.Parent() == nil)
+	t.Equal(shapes.Rect{MinX: 5, MinY: -5, MaxX: 15, MaxY: 5}, box.AABB())
+}
+
+func (t *TestSuite) TestZOrder() {
+	a := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	b := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	c := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	group := shapes.NewGroup()
+	group.Append(a)
+	group.Append(b)
+	group.Append(c)
+
+	order := func() []shapes.Shape {
+		var ordered []shapes.Shape
+		group.Each(func(s shapes.Shape) bool {
+			ordered = append(ordered, s)
+			return true
+		})
+		return ordered
+	}
+	t.Equal([]shapes.Shape{a, b, c}, order())
+	t.True(group.ShapeAt(0, 0) == c)
+
+	// Higher layers are drawn on top whatever the insertion order
+	a.SetZIndex(1)
+	t.Equal([]shapes.Shape{b, c, a}, order())
+	t.True(group.ShapeAt(0, 0) == a)
+
+	// Reordering happens inside the layer of the shape
+	t.Nil(group.BringToFront(b))
+	t.Equal([]shapes.Shape{c, b, a}, order())
+	t.Nil(group.SendToBack(b))
+	t.Equal([]shapes.Shape{b, c, a}, order())
+	t.Nil(group.Raise(b))
+	t.Equal([]shapes.Shape{c, b, a}, order())
+	t.Nil(group.Raise(b))
+	t.Equal([]shapes.Shape{c, b, a}, order())
+	t.Nil(group.Lower(b))
+	t.Equal([]shapes.Shape{b, c, a}, order())
+	t.True(group.ShapeAt(0, 0) == a)
+
+	a.SetZIndex(-1)
+	t.Equal([]shapes.Shape{a, b, c}, order())
+	t.True(group.ShapeAt(0, 0) == c)
+
+	t.True(group.Raise(shapes.NewBox(t.renderState.boxProgram, 1, 1)) != nil)
+}
+
+func (t *TestSuite) TestClone() {
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+
+	type coloredShape interface {
+		shapes.Shape
+		Color() color.Color
+		SetColor(c color.Color)
+	}
+
+	originals := []coloredShape{
+		shapes.NewBox(t.renderState.boxProgram, 10, 20),
+		shapes.NewSegment(t.renderState.segmentProgram, 0, 0, 30, 40),
+		shapes.NewCircle(t.renderState.boxProgram, 15),
+		shapes.NewEllipse(t.renderState.boxProgram, 20, 10),
+		shapes.NewPolygon(t.renderState.boxProgram, []float32{0, 0, 40, 0, 40, 30, 20, 10, 0, 30}),
+		shapes.NewPolyline(t.renderState.boxProgram, []float32{0, 0, 20, 20, 40, 0}, 4),
+	}
+	for i, s := range originals {
+		s.AttachToWorld(world)
+		s.MoveTo(float32(50*i), 30)
+		s.Rotate(15)
+		s.SetZIndex(i)
+		s.SetColor(color.RGBA{255, 0, 0, 255})
+	}
+
+	for _, s := range originals {
+		name := fmt.Sprintf("%T", s)
+		c := s.Clone().(coloredShape)
+		t.Equal(fmt.Sprintf("%T", s), fmt.Sprintf("%T", c), name)
+		t.True(c != s, name)
+
+		// Geometry, transform, color and z-order are preserved
+		t.Equal(s.Vertices(), c.Vertices(), name)
+		t.Equal(s.Transform(), c.Transform(), name)
+		t.Equal(s.AABB(), c.AABB(), name)
+		t.Equal(s.ZIndex(), c.ZIndex(), name)
+		t.Equal(s.Color(), c.Color(), name)
+
+		// Mutating the clone doesn't affect the original
+		aabb, vertices := s.AABB(), append([]float32(nil), s.Vertices()...)
+		c.Move(100, 100)
+		c.Rotate(30)
+		c.Scale(2, 2)
+		c.SetZIndex(-1)
+		c.SetColor(color.RGBA{0, 255, 0, 255})
+		t.Equal(aabb, s.AABB(), name)
+		t.Equal(vertices, s.Vertices(), name)
+		t.Equal(color.RGBA{255, 0, 0, 255}, s.Color(), name)
+		t.True(s.ZIndex() >= 0, name)
+	}
+
+	// Segments keep their stroke
+	segment := originals[1].(*shapes.Segment)
+	segment.SetWidth(6)
+	cs := segment.Clone().(*shapes.Segment)
+	t.Equal(float32(6), cs.Width())
+	cs.SetWidth(2)
+	t.Equal(float32(6), segment.Width())
+
+	// Groups are cloned with all their shapes
+	group := shapes.NewGroup()
+	for _, s := range originals {
+		group.Append(s)
+	}
+	group.Move(10, 10)
+	cg := group.Clone().(*shapes.Group)
+	t.True(cg != group)
+	t.Equal(group.Len(), cg.Len())
+	t.Equal(group.AABB(), cg.AABB())
+	t.Equal(group.Transform(), cg.Transform())
+	for i := 0; i < cg.Len(); i++ {
+		t.True(cg.GetAt(i) != group.GetAt(i))
+		t.True(cg.GetAt(i).Parent() == cg)
+		t.Equal(group.GetAt(i).ZIndex(), cg.GetAt(i).ZIndex())
+	}
+
+	aabb := group.AABB()
+	cg.Rotate(45)
+	cg.GetAt(0).Move(-50, 0)
+	cg.RemoveAt(1)
+	t.Equal(aabb, group.AABB())
+	t.Equal(len(originals), group.Len())
+	t.True(group.GetAt(0).Parent() == group)
+}
+
+func (t *TestSuite) TestVertexColors() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 20)
+	colors := []color.Color{
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+		color.White,
+	}
+	t.Nil(box.SetVertexColors(colors))
+	t.Equal(8, len(box.Vertices()))
+
+	// The number of colors must match the number of vertices
+	t.True(box.SetVertexColors(colors[:3]) != nil)
+
+	// Rendered colors are interpolated between the vertices
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+	big := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	big.AttachToWorld(world)
+	big.MoveTo(float32(w/2), 0)
+	t.Nil(big.SetVertexColors([]color.Color{color.Black, color.Black, color.White, color.White}))
+	renderer := shapes.NewSoftwareRenderer(w, h)
+	renderer.Clear(color.Black)
+	big.SetRenderer(renderer)
+	big.Draw()
+	img := renderer.Image()
+	top := img.RGBAAt(w/2, h/2-45).R
+	bottom := img.RGBAAt(w/2, h/2+45).R
+	t.True(top > 200 && bottom < 50, fmt.Sprintf("top %d bottom %d", top, bottom))
+
+	// Drawing a scaled ellipse keeps its vertex colors
+	ellipse := shapes.NewEllipse(t.renderState.boxProgram, 10, 10)
+	ellipse.AttachToWorld(world)
+	ellipse.MoveTo(float32(w/2), 0)
+	red := make([]color.Color, len(ellipse.Vertices())/2)
+	for i := range red {
+		red[i] = color.RGBA{255, 0, 0, 255}
+	}
+	t.Nil(ellipse.SetVertexColors(red))
+	ellipse.Scale(8, 8)
+	renderer.Clear(color.Black)
+	ellipse.SetRenderer(renderer)
+	ellipse.Draw()
+	t.Equal(len(red)*2, len(ellipse.Vertices()))
+	t.Equal(color.RGBA{255, 0, 0, 255}, renderer.Image().RGBAAt(w/2, h/2))
+
+	// Setting the color restores a uniform fill
+	box.SetColor(color.White)
+	t.True(box.SetVertexColors(colors) == nil)
+	box.SetColor(color.White)
+	t.Equal(color.White, box.Color())
+}
+
+func (t *TestSuite) TestGradient() {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	linear := shapes.LinearGradient{
+		X0: -50, Y0: 0, X1: 50, Y1: 0,
+		Stops: []shapes.ColorStop{{Offset: 0, Color: red}, {Offset: 1, Color: blue}},
+	}
+	t.Equal(color.NRGBA{255, 0, 0, 255}, linear.At(-60, 10))
+	t.Equal(color.NRGBA{0, 0, 255, 255}, linear.At(50, -10))
+	t.Equal(color.NRGBA{128, 0, 128, 255}, linear.At(0, 30))
+
+	// The box is split along the ends of the gradient
+	w, h := t.renderState.window.GetSize()
+	world := newWorld(w, h)
+	box := shapes.NewBox(t.renderState.boxProgram, 200, 100)
+	box.AttachToWorld(world)
+	box.MoveTo(float32(w/2), 0)
+	aabb := box.AABB()
+	box.SetGradient(linear)
+	t.True(len(box.Vertices()) > 8)
+	t.Equal(aabb, box.AABB())
+	t.True(box.Contains(float32(w/2)+90, 40))
+
+	renderer := shapes.NewSoftwareRenderer(w, h)
+	renderer.Clear(color.Black)
+	box.SetRenderer(renderer)
+	box.Draw()
+	img := renderer.Image()
+	left, right := img.RGBAAt(w/2-80, h/2), img.RGBAAt(w/2+80, h/2)
+	center := img.RGBAAt(w/2, h/2)
+	t.True(left.R == 255 && left.B == 0, fmt.Sprintf("left %v", left))
+	t.True(right.R == 0 && right.B == 255, fmt.Sprintf("right %v", right))
+	t.True(center.R > 100 && center.B > 100, fmt.Sprintf("center %v", center))
+
+	// Removing the gradient restores the geometry
+	box.SetGradient(nil)
+	t.Equal(8, len(box.Vertices()))
+
+	// Radial gradients subdivide the shape
+	circle := shapes.NewCircle(t.renderState.boxProgram, 50)
+	plain := len(circle.Vertices())
+	radial := shapes.RadialGradient{
+		Radius: 50,
+		Stops:  []shapes.ColorStop{{Offset: 0, Color: color.White}, {Offset: 1, Color: color.Black}},
+	}
+	circle.SetGradient(radial)
+	t.True(len(circle.Vertices()) > plain)
+
+	// The triangles of the circle already follow the gradient from
+	// the center to the rim, so they are barely split
+	t.True(len(circle.Vertices()) < 4*plain, fmt.Sprintf("%d vertices", len(circle.Vertices())/2))
+	t.Equal(color.NRGBA{255, 255, 255, 255}, radial.At(0, 0))
+	t.Equal(color.NRGBA{0, 0, 0, 255}, radial.At(0, 60))
+
+	// Gradients survive cloning and rebuilt geometry
+	c := circle.Clone().(*shapes.Circle)
+	t.Equal(len(circle.Vertices()), len(c.Vertices()))
+	c.SetGradient(nil)
+	t.Equal(plain, len(c.Vertices()))
+	t.True(len(circle.Vertices()) > plain)
+
+	circle.SetSegments(16)
+	t.True(circle.Gradient() != nil)
+	t.True(len(circle.Vertices()) > 18*2)
+}
+
+func (t *TestSuite) TestTexture() {
+	// A 2x1 image, red on the left and half transparent blue on
+	// the right
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 0, 255, 128})
+
+	texture, err := shapes.NewTexture(img, shapes.TextureOptions{})
+	t.Nil(err)
+	w, h := texture.Size()
+	t.Equal(2, w)
+	t.Equal(1, h)
+	t.Equal(uint32(0), texture.ID())
+	t.Equal(color.NRGBA{0, 0, 255, 128}, texture.Image().At(1, 0))
+
+	premultiplied, err := shapes.NewTexture(img, shapes.TextureOptions{PremultipliedAlpha: true})
+	t.Nil(err)
+	t.Equal(color.RGBA{0, 0, 128, 128}, premultiplied.Image().At(1, 0))
+
+	// Empty images are rejected
+	_, err = shapes.NewTexture(image.NewNRGBA(image.Rect(0, 0, 0, 4)), shapes.TextureOptions{})
+	t.True(err != nil)
+
+	// Textures are decoded from PNG streams
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(err)
+	}
+	loaded, err := shapes.LoadTexture(&buf, shapes.TextureOptions{})
+	t.Nil(err)
+	t.Equal(texture.Image(), loaded.Image())
+	_, err = shapes.LoadTexture(bytes.NewReader([]byte("not an image")), shapes.TextureOptions{})
+	t.True(err != nil)
+	_, err = shapes.LoadTextureFile("missing.png", shapes.TextureOptions{})
+	t.True(err != nil)
+
+	// Client textures have no pixels
+	client := shapes.TextureFromID(42, 64, 32)
+	t.Equal(uint32(42), client.ID())
+	t.Equal(64, client.Width())
+	t.Equal(32, client.Height())
+	t.True(client.Image() == nil)
+
+	// Repeated textures tile the box
+	width, height := t.renderState.window.GetSize()
+	world := newWorld(width, height)
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	renderer.Clear(color.Black)
+	box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	box.SetRenderer(renderer)
+	box.AttachToWorld(world)
+	box.MoveTo(float32(width/2), 0)
+	repeated, err := shapes.NewTexture(img, shapes.TextureOptions{WrapS: shapes.RepeatWrap})
+	t.Nil(err)
+	t.Nil(box.SetTexture(repeated, []float32{0, 0, 2, 0, 0, 1, 2, 1}))
+	t.True(box.Texture() == repeated)
+
+	// Texture coordinates must match the vertices
+	t.True(box.SetTexture(nil, nil) == nil && box.SetTexture(repeated, []float32{0, 0, 1, 1}) != nil)
+	t.True(box.Texture() == nil)
+	t.Nil(box.SetTexture(repeated, []float32{0, 0, 2, 0, 0, 1, 2, 1}))
+	group := shapes.NewGroup()
+	group.Append(shapes.NewBox(t.renderState.boxProgram, 10, 10))
+	group.Append(shapes.NewCircle(t.renderState.boxProgram, 10))
+	t.True(group.SetTexture(repeated, []float32{0, 0, 1, 0, 0, 1, 1, 1}) != nil)
+	t.True(group.GetAt(0).(*shapes.Box).Texture() == repeated)
+	t.Nil(group.SetTexture(repeated, nil))
+	box.Draw()
+	result := renderer.Image()
+	for i, x := range []int{-40, -15, 10, 35} {
+		c := result.RGBAAt(width/2+x, height/2)
+		if i%2 == 0 {
+			t.True(c.R == 255 && c.B == 0, fmt.Sprintf("%d: %v", x, c))
+		} else {
+			// Half transparent blue blended on black
+			t.True(c.R == 0 && c.B == 128, fmt.Sprintf("%d: %v", x, c))
+		}
+	}
+
+	// Removing the texture restores the color
+	t.Nil(box.SetTexture(nil, nil))
+	t.True(box.Texture() == nil)
+	renderer.Clear(color.Black)
+	box.Draw()
+	t.Equal(shapes.DefaultColor, result.RGBAAt(width/2, height/2))
+}
+
+// waitAnimation is an animation doing nothing for a while.
+type waitAnimation struct {
+	duration, elapsed time.Duration
+}
+
+func (w *waitAnimation) Update(dt time.Duration) bool {
+	_, done := w.Advance(dt)
+	return done
+}
+
+func (w *waitAnimation) Advance(dt time.Duration) (time.Duration, bool) {
+	w.elapsed += dt
+	if w.elapsed < w.duration {
+		return 0, false
+	}
+	return w.elapsed - w.duration, true
+}
+
+func (w *waitAnimation) Reset() {
+	w.elapsed = 0
+}
+
+// uniformImage returns an image of the given size filled with c.
+func uniformImage(width, height int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.ZP, draw.Src)
+	return img
+}
+
+func (t *TestSuite) TestTextureAtlas() {
+	red, green, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 255, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	atlas, err := shapes.NewTextureAtlas(map[string]image.Image{
+		"red":   uniformImage(30, 20, red),
+		"green": uniformImage(10, 40, green),
+		"blue":  uniformImage(25, 25, blue),
+	}, shapes.TextureOptions{})
+	t.Nil(err)
+	t.Equal([]string{"blue", "green", "red"}, atlas.Names())
+	t.True(atlas.Region("yellow") == nil)
+
+	// Power-of-two texture, regions don't overlap and hold their
+	// images
+	w, h := atlas.Texture().Size()
+	t.True(w&(w-1) == 0 && h&(h-1) == 0, fmt.Sprintf("%dx%d", w, h))
+	colors := map[string]color.NRGBA{"red": red, "green": green, "blue": blue}
+	var rects []image.Rectangle
+	for name, c := range colors {
+		r := atlas.Region(name)
+		t.Equal(name, r.Name)
+		t.True(r.Texture == atlas.Texture())
+		rect := image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+		for _, other := range rects {
+			t.False(rect.Overlaps(other), name)
+		}
+		rects = append(rects, rect)
+		sheet := atlas.Texture().Image()
+		t.Equal(c, sheet.At(rect.Min.X, rect.Min.Y), name)
+		t.Equal(c, sheet.At(rect.Max.X-1, rect.Max.Y-1), name)
+	}
+	t.Equal(30, atlas.Region("red").Width)
+	t.Equal(20, atlas.Region("red").Height)
+
+	// Images don't fit in small textures
+	max := shapes.MaxAtlasSize
+	shapes.MaxAtlasSize = 32
+	_, err = shapes.NewTextureAtlas(map[string]image.Image{"big": uniformImage(40, 10, red)}, shapes.TextureOptions{})
+	t.True(err != nil)
+	shapes.MaxAtlasSize = max
+
+	// Boxes show the image of the region
+	width, height := t.renderState.window.GetSize()
+	world := newWorld(width, height)
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	renderer.Clear(color.Black)
+	box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+	box.SetRenderer(renderer)
+	box.AttachToWorld(world)
+	box.MoveTo(float32(width/2), 0)
+	t.Nil(box.SetTextureRegion(atlas.Region("green")))
+	t.True(box.SetTextureRegion(atlas.Region("missing")) != nil)
+	box.Draw()
+	for _, p := range []image.Point{{-45, -45}, {45, -45}, {0, 0}, {-45, 45}, {45, 45}} {
+		t.Equal(color.RGBA{0, 255, 0, 255}, renderer.Image().RGBAAt(width/2+p.X, height/2+p.Y), fmt.Sprint(p))
+	}
+}
+
+func (t *TestSuite) TestTextureAtlasDescriptor() {
+	// A 4x2 sheet: a 2x2 image on the left, a 1x2 image stored
+	// rotated on the top right
+	sheet := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	texture, err := shapes.NewTexture(sheet, shapes.TextureOptions{})
+	t.Nil(err)
+
+	hash := `{
+		"frames": {
+			"square": {"frame": {"x": 0, "y": 0, "w": 2, "h": 2}, "rotated": false},
+			"bar": {"frame": {"x": 2, "y": 0, "w": 1, "h": 2}, "rotated": true}
+		},
+		"meta": {"image": "sheet.png"}
+	}`
+	array := `{
+		"frames": [
+			{"filename": "square", "frame": {"x": 0, "y": 0, "w": 2, "h": 2}},
+			{"filename": "bar", "frame": {"x": 2, "y": 0, "w": 1, "h": 2}, "rotated": true}
+		]
+	}`
+	for _, descriptor := range []string{hash, array} {
+		atlas, err := shapes.LoadTextureAtlas(texture, strings.NewReader(descriptor))
+		t.Nil(err)
+		t.Equal([]string{"bar", "square"}, atlas.Names())
+
+		// Bottom-left, bottom-right, top-left, top-right
+		t.Equal([]float32{0, 0, 0.5, 0, 0, 1, 0.5, 1}, atlas.Region("square").TexCoords())
+
+		// The rotated bar covers the top right quarter of the
+		// sheet
+		bar := atlas.Region("bar")
+		t.True(bar.Rotated)
+		t.Equal(1, bar.Width)
+		t.Equal([]float32{0.5, 1, 0.5, 0.5, 1, 1, 1, 0.5}, bar.TexCoords())
+	}
+
+	_, err = shapes.LoadTextureAtlas(texture, strings.NewReader("{"))
+	t.True(err != nil)
+	_, err = shapes.LoadTextureAtlasFile("missing.json", shapes.TextureOptions{})
+	t.True(err != nil)
+}
+
+func (t *TestSuite) TestSprite() {
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	images := make(map[string]image.Image)
+	for i, c := range colors {
+		images[fmt.Sprint(i)] = uniformImage(8, 8, c)
+	}
+	atlas, err := shapes.NewTextureAtlas(images, shapes.TextureOptions{})
+	t.Nil(err)
+	frames := shapes.NewFrames(100*time.Millisecond, atlas.Region("0"), atlas.Region("1"), atlas.Region("2"))
+
+	width, height := t.renderState.window.GetSize()
+	world := newWorld(width, height)
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	sprite := shapes.NewSprite(t.renderState.boxProgram, 50, 50, frames)
+	sprite.SetRenderer(renderer)
+	sprite.AttachToWorld(world)
+	sprite.MoveTo(float32(width/2), 0)
+	shown := func() color.RGBA {
+		renderer.Clear(color.Black)
+		sprite.Draw()
+		return renderer.Image().RGBAAt(width/2, height/2)
+	}
+
+	// Paused on the first frame
+	t.False(sprite.Playing())
+	sprite.Update(time.Second)
+	t.Equal(0, sprite.Frame())
+	t.Equal(color.RGBA{255, 0, 0, 255}, shown())
+
+	// Loop
+	loops := 0
+	sprite.OnLoop(func() { loops++ })
+	sprite.Play()
+	sprite.Update(250 * time.Millisecond)
+	t.Equal(2, sprite.Frame())
+	t.Equal(color.RGBA{0, 0, 255, 255}, shown())
+	sprite.Update(50 * time.Millisecond)
+	t.Equal(0, sprite.Frame())
+	t.Equal(1, loops)
+
+	// Pause and seek
+	sprite.Pause()
+	sprite.Update(time.Second)
+	t.Equal(0, sprite.Frame())
+	sprite.Seek(1)
+	t.Equal(color.RGBA{0, 255, 0, 255}, shown())
+	sprite.Seek(10)
+	t.Equal(2, sprite.Frame())
+
+	// Ping-pong
+	sprite.SetMode(shapes.PingPongMode)
+	sprite.Seek(0)
+	sprite.Play()
+	var sequence []int
+	for i := 0; i < 6; i++ {
+		sprite.Update(100 * time.Millisecond)
+		sequence = append(sequence, sprite.Frame())
+	}
+	t.Equal([]int{1, 2, 1, 0, 1, 2}, sequence)
+	t.Equal(2, loops)
+
+	// Once
+	completed := 0
+	sprite.OnComplete(func() { completed++ })
+	sprite.SetMode(shapes.OnceMode)
+	sprite.Seek(0)
+	sprite.Update(time.Second)
+	t.Equal(2, sprite.Frame())
+	t.False(sprite.Playing())
+	t.Equal(1, completed)
+	sprite.Play()
+	t.Equal(0, sprite.Frame())
+
+	// Clones animate independently
+	c := sprite.Clone().(*shapes.Sprite)
+	c.Update(150 * time.Millisecond)
+	t.Equal(1, c.Frame())
+	t.Equal(0, sprite.Frame())
+
+	// Pausing on the last frame doesn't complete the animation
+	sprite.Update(250 * time.Millisecond)
+	t.Equal(2, sprite.Frame())
+	sprite.Pause()
+	sprite.Play()
+	t.Equal(2, sprite.Frame())
+	sprite.Update(40 * time.Millisecond)
+	t.True(sprite.Playing())
+	sprite.Update(10 * time.Millisecond)
+	t.False(sprite.Playing())
+	t.Equal(2, completed)
+
+	// Reset restarts the animation
+	sprite.Reset()
+	t.Equal(0, sprite.Frame())
+	t.False(sprite.Playing())
+}
+
+func (t *TestSuite) TestTween() {
+	ms := time.Millisecond
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+
+	// Tweens start from the current state after the delay
+	started, completed := 0, 0
+	move := tween.MoveTo(box, 100, 50, 100*ms).
+		Delay(50 * ms).
+		OnStart(func() { started++ }).
+		OnComplete(func() { completed++ })
+	t.False(move.Update(40 * ms))
+	t.Equal(0, started)
+	t.False(move.Update(60 * ms))
+	t.Equal(1, started)
+	x, y := box.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(25), y)
+	t.True(move.Update(100 * ms))
+	x, y = box.Center()
+	t.Equal(float32(100), x)
+	t.Equal(float32(50), y)
+	t.Equal(1, completed)
+	t.True(move.Update(100 * ms))
+	t.Equal(1, completed)
+
+	// Easings
+	for _, ease := range []tween.Easing{
+		tween.Linear, tween.QuadIn, tween.QuadOut, tween.QuadInOut,
+		tween.CubicIn, tween.CubicOut, tween.CubicInOut,
+		tween.SineIn, tween.SineOut, tween.SineInOut,
+		tween.ExpoIn, tween.ExpoOut, tween.BackIn, tween.BackOut,
+		tween.ElasticOut, tween.BounceIn, tween.BounceOut,
+	} {
+		t.True(abs(ease(0)) < 1e-3 && abs(ease(1)-1) < 1e-3, fmt.Sprint(ease(0), ease(1)))
+	}
+	t.Equal(float32(0.25), tween.QuadIn(0.5))
+
+	// Sequences carry the time left by each tween to the next one
+	box.MoveTo(0, 0)
+	sequence := tween.NewSequence(
+		tween.MoveBy(box, 10, 0, 100*ms),
+		tween.RotateTo(box, 90, 100*ms),
+	)
+	t.False(sequence.Update(150 * ms))
+	x, _ = box.Center()
+	t.Equal(float32(10), x)
+	t.Equal(float32(45), box.Angle())
+	t.True(sequence.Update(50 * ms))
+	t.Equal(float32(90), box.Angle())
+
+	// Parallel groups complete with the longest tween
+	box.SetColor(color.RGBA{0, 0, 0, 255})
+	parallel := tween.NewParallel(
+		tween.ScaleTo(box, 3, 2, 100*ms),
+		tween.ColorTo(box, color.RGBA{255, 255, 255, 255}, 200*ms),
+	)
+	t.False(parallel.Update(100 * ms))
+	t.Equal(float32(3), box.Transform().ScaleX)
+	t.Equal(float32(2), box.Transform().ScaleY)
+	t.Equal(color.NRGBA{128, 128, 128, 255}, box.Color())
+	t.True(parallel.Update(100 * ms))
+	t.Equal(color.NRGBA{255, 255, 255, 255}, box.Color())
+
+	// Repeated yoyo tweens go back and forth
+	fade := tween.FadeTo(box, 0, 100*ms).Repeat(1).Yoyo(true)
+	fade.Update(100 * ms)
+	_, _, _, a := box.Color().RGBA()
+	t.Equal(uint32(0), a)
+	t.False(fade.Update(50 * ms))
+	t.True(fade.Update(50 * ms))
+	_, _, _, a = box.Color().RGBA()
+	t.Equal(uint32(0xffff), a)
+
+	// Animations other than tweens play in sequences
+	wait := &waitAnimation{duration: 100 * ms}
+	box.MoveTo(0, 0)
+	waitAndMove := tween.NewSequence(wait, tween.MoveTo(box, 10, 0, 100*ms))
+	t.False(waitAndMove.Update(150 * ms))
+	x, _ = box.Center()
+	t.Equal(float32(5), x)
+	t.True(waitAndMove.Update(50 * ms))
+	waitAndMove.Reset()
+	t.Equal(time.Duration(0), wait.elapsed)
+
+	// Endless sequences
+	loops := 0
+	endless := tween.NewSequence(
+		tween.New(100*ms, nil, func(float32) {}).OnComplete(func() { loops++ }),
+	).Repeat(tween.Forever)
+	t.False(endless.Update(350 * ms))
+	t.Equal(3, loops)
+
+	// The manager discards completed animations
+	m := new(tween.Manager)
+	m.Add(tween.RotateBy(box, 10, 100*ms))
+	m.Add(endless)
+	m.Update(100 * ms)
+	t.Equal(1, m.Len())
+	m.Remove(endless)
+	t.Equal(0, m.Len())
+}
+
+// abs returns the absolute value of v.
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (t *TestSuite) TestOpacity() {
+	width, height := t.renderState.window.GetSize()
+	world := newWorld(width, height)
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	newBox := func(c color.Color) *shapes.Box {
+		box := shapes.NewBox(t.renderState.boxProgram, 40, 40)
+		box.SetRenderer(renderer)
+		box.AttachToWorld(world)
+		box.MoveTo(float32(width/2), 0)
+		box.SetColor(c)
+		return box
+	}
+	center := func() color.RGBA {
+		return renderer.Image().RGBAAt(width/2, height/2)
+	}
+
+	// Shapes are opaque by default, opacity is clamped
+	box := newBox(color.White)
+	t.Equal(float32(1), box.Opacity())
+	box.SetOpacity(2)
+	t.Equal(float32(1), box.Opacity())
+	box.SetOpacity(0.5)
+	renderer.Clear(color.Black)
+	box.Draw()
+	t.Equal(color.RGBA{128, 128, 128, 255}, center())
+
+	// Transparent colors are blended
+	renderer.Clear(color.Black)
+	newBox(color.NRGBA{255, 0, 0, 128}).Draw()
+	t.Equal(color.RGBA{128, 0, 0, 255}, center())
+
+	// Group opacity multiplies into the children
+	group := shapes.NewGroup()
+	group.Append(box)
+	group.SetOpacity(0.5)
+	renderer.Clear(color.Black)
+	group.Draw()
+	t.Equal(color.RGBA{64, 64, 64, 255}, center())
+	t.Equal(float32(0.5), group.Clone().Opacity())
+
+	// Blend modes
+	gray := color.RGBA{128, 128, 128, 255}
+	for _, test := range []struct {
+		mode     shapes.BlendMode
+		src      color.Color
+		expected color.RGBA
+	}{
+		{shapes.NormalBlend, gray, gray},
+		{shapes.AdditiveBlend, gray, color.RGBA{192, 255, 255, 255}},
+		{shapes.MultiplyBlend, gray, color.RGBA{32, 128, 128, 255}},
+		{shapes.ScreenBlend, gray, color.RGBA{160, 255, 255, 255}},
+	} {
+		renderer.Clear(color.RGBA{64, 255, 255, 255})
+		b := newBox(test.src)
+		b.SetBlendMode(test.mode)
+		b.Draw()
+		t.Equal(test.expected, center(), fmt.Sprint(test.mode))
+		t.Equal(test.mode, b.Clone().(*shapes.Box).BlendMode())
+	}
+
+	// Premultiplied textures
+	texel := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	texel.Pix = []uint8{64, 0, 0, 128}
+	renderer.Clear(color.RGBA{64, 255, 255, 255})
+	b := newBox(color.White)
+	texture, err := shapes.NewTexture(texel, shapes.TextureOptions{})
+	t.Nil(err)
+	b.SetTexture(texture, nil)
+	b.SetBlendMode(shapes.PremultipliedBlend)
+	b.Draw()
+	t.Equal(color.RGBA{96, 127, 127, 255}, center())
+
+	// Opacity and blend mode split the batches
+	counter := &countingRenderer{Renderer: renderer}
+	batched := shapes.NewGroup()
+	for i := 0; i < 3; i++ {
+		b := newBox(color.White)
+		b.Move(float32(i*50), 0)
+		batched.Append(b)
+	}
+	batched.GetAt(2).SetOpacity(0.5)
+	batched.SetRenderer(shapes.NewBatchRenderer(counter))
+	batched.Draw()
+	t.Equal(2, counter.calls)
+
+	// Tweens fade groups
+	fade := tween.OpacityTo(group, 0, 100*time.Millisecond)
+	fade.Update(50 * time.Millisecond)
+	t.Equal(float32(0.25), group.Opacity())
+}
+
+func (t *TestSuite) TestCamera() {
+	width, height := t.renderState.window.GetSize()
+	camera := shapes.NewCamera2D(float32(width), float32(height))
+	near := func(a, b float32) bool { return abs(a-b) < 0.01 }
+
+	// The camera looks at the center of the viewport
+	x, y := camera.ScreenToWorld(float32(width/2), float32(height/2))
+	t.True(near(0, x) && near(0, y))
+
+	// Panned, zoomed and rotated cameras convert touch coordinates
+	// both ways
+	camera.MoveTo(100, 50)
+	camera.SetZoom(2)
+	camera.SetAngle(90)
+	x, y = camera.WorldToScreen(100, 60)
+	t.True(near(float32(width/2+20), x) && near(float32(height/2), y))
+	x, y = camera.ScreenToWorld(x, y)
+	t.True(near(100, x) && near(60, y))
+	sx, sy := shapes.ScreenToWorld(camera, 10, 20, width, height)
+	x, y = camera.ScreenToWorld(10, 20)
+	t.True(near(sx, x) && near(sy, y))
+
+	// Shapes are transformed by the model matrix before the view
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.SetRenderer(renderer)
+	box.SetColor(color.White)
+	box.MoveTo(100, 50)
+	camera.SetAngle(0)
+	box.AttachToWorld(camera)
+	renderer.Clear(color.Black)
+	box.Draw()
+	img := renderer.Image()
+	t.Equal(color.RGBA{255, 255, 255, 255}, img.RGBAAt(width/2+15, height/2-15))
+	t.Equal(color.RGBA{0, 0, 0, 255}, img.RGBAAt(width/2+25, height/2))
+
+	// Following
+	camera = shapes.NewCamera2D(float32(width), float32(height))
+	camera.Follow(box, 0)
+	camera.Update(time.Second)
+	x, y = camera.Position()
+	t.True(near(100, x) && near(50, y))
+	camera.MoveTo(0, 0)
+	camera.Follow(box, time.Second)
+	camera.Update(time.Second)
+	x, _ = camera.Position()
+	t.True(near(63.21, x))
+	camera.Follow(nil, 0)
+	camera.Update(time.Second)
+	x, _ = camera.Position()
+	t.True(near(63.21, x))
+
+	// Bounds keep the visible area inside
+	camera.SetBounds(shapes.Rect{MaxX: 1000, MaxY: 1000})
+	camera.MoveTo(0, 0)
+	x, y = camera.Position()
+	t.True(near(float32(width/2), x) && near(float32(height/2), y))
+	camera.SetBounds(shapes.Rect{MaxX: 100, MaxY: 100})
+	x, y = camera.Position()
+	t.True(near(50, x) && near(50, y))
+	camera.ClearBounds()
+	camera.MoveTo(-10, -10)
+	x, y = camera.Position()
+	t.True(near(-10, x) && near(-10, y))
+}
+
+func (t *TestSuite) TestAttachToWorld() {
+	width, height := t.renderState.window.GetSize()
+	camera := shapes.NewCamera2D(float32(width), float32(height))
+	renderer := shapes.NewSoftwareRenderer(width, height)
+	white := color.RGBA{255, 255, 255, 255}
+
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.SetRenderer(renderer)
+	box.SetColor(color.White)
+	box.AttachToWorld(camera)
+	t.True(box.World() == camera)
+	renderer.Clear(color.Black)
+	box.Draw()
+	t.Equal(white, renderer.Image().RGBAAt(width/2, height/2))
+
+	// Moving the camera moves the shapes without attaching them
+	// again
+	camera.MoveTo(100, 0)
+	renderer.Clear(color.Black)
+	box.Draw()
+	t.Equal(white, renderer.Image().RGBAAt(width/2-100, height/2))
+	t.True(box.Clone().(*shapes.Box).World() == camera)
+
+	// Groups attach the shapes appended later
+	group := shapes.NewGroup()
+	group.AttachToWorld(camera)
+	inner := shapes.NewGroup()
+	group.Append(inner)
+	b := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	inner.Append(b)
+	t.True(inner.World() == camera)
+	t.True(b.World() == camera)
+	t.True(group.Clone().(*shapes.Group).World() == camera)
+}
+
+func (t *TestSuite) TestViewport() {
+	width, height := t.renderState.window.GetSize()
+	near := func(a, b float32) bool { return abs(a-b) < 0.01 }
+
+	// Mandala window events resize the viewport
+	v := viewport.New(160, 160, viewport.Letterbox)
+	v.HandleEvent(mandala.NativeWindowCreatedEvent{Window: t.renderState.window})
+	w, h := v.Size()
+	t.Equal(width, w)
+	t.Equal(height, h)
+
+	// Policies, for a virtual resolution of 160x160 on a 320x480
+	// window
+	v.Resize(320, 480)
+	t.Equal(image.Rect(0, 80, 320, 400), v.Rect())
+	x, y := v.ScreenToWorld(0, 80)
+	t.True(near(-80, x) && near(80, y))
+	x, y = v.WorldToScreen(x, y)
+	t.True(near(0, x) && near(80, y))
+	for _, test := range []struct {
+		policy         viewport.Policy
+		scaleX, scaleY float32
+		visibleW       float32
+		visibleH       float32
+	}{
+		{viewport.Letterbox, 2, 2, 160, 160},
+		{viewport.Fit, 2, 2, 160, 240},
+		{viewport.Fill, 3, 3, 106.67, 160},
+		{viewport.Stretch, 2, 3, 160, 160},
+	} {
+		v.SetPolicy(test.policy)
+		sx, sy := v.Scale()
+		vw, vh := v.VisibleSize()
+		t.True(near(test.scaleX, sx) && near(test.scaleY, sy), fmt.Sprint(test.policy))
+		t.True(near(test.visibleW, vw) && near(test.visibleH, vh), fmt.Sprint(test.policy))
+	}
+
+	// With no virtual resolution world units are pixels
+	v.SetVirtualSize(0, 0)
+	vw, vh := v.VisibleSize()
+	t.True(near(320, vw) && near(480, vh))
+
+	// Density-independent units
+	v = viewport.New(160, 240, viewport.Fit)
+	v.Resize(320, 480)
+	v.SetDensity(2)
+	t.Equal(float32(10), v.Dp(10))
+
+	// Shapes are scaled to the window
+	renderer := shapes.NewSoftwareRenderer(320, 480)
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.SetRenderer(renderer)
+	box.SetColor(color.White)
+	box.AttachToWorld(v)
+	renderer.Clear(color.Black)
+	box.Draw()
+	t.Equal(color.RGBA{255, 255, 255, 255}, renderer.Image().RGBAAt(175, 240))
+	t.Equal(color.RGBA{0, 0, 0, 255}, renderer.Image().RGBAAt(185, 240))
+
+	// Cameras see the visible area
+	camera := shapes.NewCamera2D(0, 0)
+	v.SetCamera(camera)
+	cw, ch := camera.Viewport()
+	t.Equal(float32(160), cw)
+	t.Equal(float32(240), ch)
+	camera.MoveTo(10, 0)
+	x, y = v.ScreenToWorld(160, 240)
+	t.True(near(10, x) && near(0, y))
+	camera.SetAngle(90)
+	camera.SetZoom(2)
+	x, y = v.ScreenToWorld(200, 240)
+	t.True(near(10, x) && near(10, y), fmt.Sprint(x, y))
+	x, y = v.WorldToScreen(x, y)
+	t.True(near(200, x) && near(240, y), fmt.Sprint(x, y))
+
+	// The bounds of the camera apply to the fractional visible
+	// area
+	v.Resize(320, 479)
+	camera.SetAngle(0)
+	camera.SetZoom(1)
+	camera.SetBounds(shapes.Rect{MinX: 0, MinY: 0, MaxX: 200, MaxY: 200})
+	camera.MoveTo(0, 0)
+	x, _ = camera.Position()
+	t.True(near(80.17, x), fmt.Sprint(x))
+}
+
+func (t *TestSuite) TestCulling() {
+	camera := shapes.NewCamera2D(320, 480)
+	visible, ok := shapes.VisibleRect(camera)
+	t.True(ok)
+	t.True(abs(visible.MinX+160) < 0.01 && abs(visible.MaxY-240) < 0.01)
+	t.True(abs(visible.Dx()-320) < 0.01 && abs(visible.Dy()-480) < 0.01)
+
+	counter := &countingRenderer{Renderer: shapes.NewSoftwareRenderer(320, 480)}
+	newBox := func(x float32) *shapes.Box {
+		box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+		box.MoveTo(x, 0)
+		return box
+	}
+	group := shapes.NewGroup()
+	group.SetRenderer(counter)
+	group.AttachToWorld(camera)
+	group.Append(newBox(0))
+	group.Append(newBox(1000))
+	inner := shapes.NewGroup()
+	inner.Append(newBox(0))
+	inner.Append(newBox(-1000))
+	group.Append(inner)
+
+	// Without culling all the shapes are drawn
+	group.Draw()
+	t.Equal(shapes.DrawStats{Drawn: 4}, group.Stats())
+	t.Equal(4, counter.calls)
+
+	// Nested groups are culled too
+	counter.calls = 0
+	group.SetCulling(true)
+	group.Draw()
+	t.Equal(shapes.DrawStats{Drawn: 2, Culled: 2}, group.Stats())
+	t.Equal(shapes.DrawStats{Drawn: 1, Culled: 1}, inner.Stats())
+	t.Equal(2, counter.calls)
+
+	// Culling follows the camera
+	counter.calls = 0
+	camera.MoveTo(1000, 0)
+	group.Draw()
+	t.Equal(shapes.DrawStats{Drawn: 1, Culled: 2}, group.Stats())
+	t.Equal(1, counter.calls)
+	t.True(group.Clone().(*shapes.Group).Culling())
+
+	// Indexed groups only test the shapes near the visible area
+	counter.calls = 0
+	group.SetSpatialIndex(100)
+	group.Draw()
+	t.Equal(shapes.DrawStats{Drawn: 1, Culled: 2}, group.Stats())
+	t.Equal(1, counter.calls)
+	counter.calls = 0
+	camera.MoveTo(0, 0)
+	group.Draw()
+	t.Equal(shapes.DrawStats{Drawn: 2, Culled: 2}, group.Stats())
+	t.Equal(2, counter.calls)
+}
+
+func (t *TestSuite) TestSpatialIndex() {
+	newBox := func(x, y float32) *shapes.Box {
+		box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+		box.MoveTo(x, y)
+		return box
+	}
+	group := shapes.NewGroup()
+	boxes := make(map[[2]int]*shapes.Box)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			boxes[[2]int{i, j}] = newBox(float32(i*50), float32(j*50))
+			group.Append(boxes[[2]int{i, j}])
+		}
+	}
+
+	// Queries give the same results with and without the index
+	for _, cellSize := range []float32{0, 50, 7} {
+		group.SetSpatialIndex(cellSize)
+		t.Equal(cellSize, group.SpatialIndex())
+		t.Equal(2, len(group.ShapesIn(shapes.Rect{MinX: -5, MinY: -5, MaxX: 60, MaxY: 5})))
+		t.Equal(100, len(group.ShapesIn(shapes.Rect{MinX: -1000, MinY: -1000, MaxX: 1000, MaxY: 1000})))
+		t.Equal(0, len(group.ShapesIn(shapes.Rect{MinX: 2000, MinY: 2000, MaxX: 3000, MaxY: 3000})))
+		t.Equal(1, len(group.ShapesAt(50, 50)))
+		t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+		t.True(group.Nearest(120, 0) == boxes[[2]int{2, 0}])
+		t.True(group.Nearest(-300, 800) == boxes[[2]int{0, 9}])
+		t.True(group.Nearest(100000, 0) == boxes[[2]int{9, 0}])
+		t.True(group.Nearest(-100000, -100000) == boxes[[2]int{0, 0}])
+	}
+
+	// The index follows the shapes
+	group.SetSpatialIndex(50)
+	moved := boxes[[2]int{2, 0}]
+	moved.MoveTo(1000, 1000)
+	t.Equal(0, len(group.ShapesAt(100, 0)))
+	t.True(group.ShapeAt(1000, 1000) == moved)
+	t.True(group.Nearest(990, 990) == moved)
+	group.Remove(moved)
+	t.Nil(group.ShapeAt(1000, 1000))
+	t.True(group.Nearest(990, 990) == boxes[[2]int{9, 9}])
+
+	// Queries are in world coordinates
+	group.Move(10, 0)
+	t.True(group.ShapeAt(58, 50) == boxes[[2]int{1, 1}])
+	group.Move(-10, 0)
+
+	// The topmost shape is found
+	top := newBox(50, 50)
+	group.Append(top)
+	t.True(group.ShapeAt(50, 50) == top)
+	boxes[[2]int{1, 1}].SetZIndex(1)
+	t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+	boxes[[2]int{1, 1}].SetZIndex(0)
+	t.True(group.ShapeAt(50, 50) == top)
+
+	// Reordering the shapes changes the topmost one
+	group.BringToFront(boxes[[2]int{1, 1}])
+	t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+	group.Lower(boxes[[2]int{1, 1}])
+	t.True(group.ShapeAt(50, 50) == top)
+	group.SendToBack(top)
+	t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+	group.RemoveAt(0)
+	group.InsertAt(1, top)
+	t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+	group.Raise(top)
+	t.True(group.ShapeAt(50, 50) == boxes[[2]int{1, 1}])
+	group.Remove(boxes[[2]int{1, 1}])
+	t.True(group.ShapeAt(50, 50) == top)
+
+	// Nested groups report the changes of their shapes
+	inner := shapes.NewGroup()
+	nested := newBox(0, 0)
+	inner.Append(nested)
+	group.Append(inner)
+	nested.MoveTo(-500, -500)
+	t.True(group.ShapeAt(-500, -500) == inner)
+	t.Equal(float32(50), group.Clone().(*shapes.Group).SpatialIndex())
+}
+
 // func getBufferDataFromImage(img image.Image) ([]byte, int, int) {
 // 	bounds := img.Bounds()
 // 	imgWidth, imgHeight := bounds.Size().X, bounds.Size().Y
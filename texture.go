@@ -0,0 +1,259 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"github.com/aded/shapes/internal/mathf"
+	gl "github.com/remogatto/opengles2"
+)
+
+// TextureFilter is the function used to sample a texture when it's
+// minified or magnified.
+type TextureFilter int
+
+const (
+	// NearestFilter samples the nearest texel.
+	NearestFilter TextureFilter = iota
+
+	// LinearFilter interpolates the four nearest texels.
+	LinearFilter
+)
+
+// TextureWrap is the behaviour of a texture sampled outside the [0,
+// 1] range.
+type TextureWrap int
+
+const (
+	// ClampWrap repeats the texels on the edges.
+	ClampWrap TextureWrap = iota
+
+	// RepeatWrap tiles the texture.
+	RepeatWrap
+
+	// MirroredRepeatWrap tiles the texture mirroring every other
+	// tile.
+	MirroredRepeatWrap
+)
+
+// TextureOptions controls how a texture is sampled and uploaded.
+type TextureOptions struct {
+	// Filters used when the texture is minified or magnified
+	MinFilter, MagFilter TextureFilter
+
+	// Wrap modes along the s and t axes
+	WrapS, WrapT TextureWrap
+
+	// Mipmaps generates the mipmaps of the texture, used when it's
+	// minified. OpenGL ES 2 supports mipmaps of power-of-two
+	// textures only.
+	Mipmaps bool
+
+	// PremultipliedAlpha uploads the color components multiplied
+	// by alpha, to be blended as premultiplied colors. Otherwise
+	// the components are uploaded unchanged.
+	PremultipliedAlpha bool
+}
+
+// Texture is an image stored on the GPU. The image is uploaded by
+// GLRenderer the first time a shape using the texture is drawn. The
+// pixels are kept in memory so that the texture can be drawn by
+// SoftwareRenderer and uploaded again after Delete.
+type Texture struct {
+	// OpenGL name of the texture, 0 before the upload
+	id uint32
+
+	// Size of the texture in pixels
+	width, height int
+
+	// Pixels in RGBA order, nil for textures created by the
+	// client code
+	pix []uint8
+
+	options TextureOptions
+}
+
+// NewTexture returns a texture holding a copy of img. It returns an
+// error if the image is empty.
+func NewTexture(img image.Image, options TextureOptions) (*Texture, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("cannot create a texture from an empty %dx%d image", b.Dx(), b.Dy())
+	}
+	t := &Texture{width: b.Dx(), height: b.Dy(), options: options}
+	if options.PremultipliedAlpha {
+		rgba := image.NewRGBA(image.Rect(0, 0, t.width, t.height))
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+		t.pix = rgba.Pix
+	} else {
+		nrgba := image.NewNRGBA(image.Rect(0, 0, t.width, t.height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+		t.pix = nrgba.Pix
+	}
+	return t, nil
+}
+
+// LoadTexture decodes a PNG or JPEG image from r and returns it as a
+// texture.
+func LoadTexture(r io.Reader, options TextureOptions) (*Texture, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewTexture(img, options)
+}
+
+// LoadTextureFile loads a PNG or JPEG image from the given file and
+// returns it as a texture.
+func LoadTextureFile(filename string, options TextureOptions) (*Texture, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadTexture(file, options)
+}
+
+// TextureFromID wraps a texture of the given size already created by
+// the client code on the OpenGL context. The texture can't be drawn
+// by SoftwareRenderer.
+func TextureFromID(id uint32, width, height int) *Texture {
+	return &Texture{id: id, width: width, height: height}
+}
+
+// ID returns the OpenGL name of the texture, 0 if the texture has not
+// been uploaded yet.
+func (t *Texture) ID() uint32 {
+	return t.id
+}
+
+// Size returns the width and the height of the texture in pixels.
+func (t *Texture) Size() (int, int) {
+	return t.width, t.height
+}
+
+// Width returns the width of the texture in pixels.
+func (t *Texture) Width() int {
+	return t.width
+}
+
+// Height returns the height of the texture in pixels.
+func (t *Texture) Height() int {
+	return t.height
+}
+
+// Options returns the options of the texture.
+func (t *Texture) Options() TextureOptions {
+	return t.options
+}
+
+// Image returns the pixels of the texture, an *image.RGBA if the
+// alpha is premultiplied, an *image.NRGBA otherwise. It returns nil
+// for textures created with TextureFromID.
+func (t *Texture) Image() image.Image {
+	if t.pix == nil {
+		return nil
+	}
+	r := image.Rect(0, 0, t.width, t.height)
+	if t.options.PremultipliedAlpha {
+		return &image.RGBA{Pix: t.pix, Stride: t.width * 4, Rect: r}
+	}
+	return &image.NRGBA{Pix: t.pix, Stride: t.width * 4, Rect: r}
+}
+
+// Upload creates the texture on the OpenGL context and uploads the
+// pixels, if it has not been done yet. It must be called from the
+// thread owning the context. There's no need to call it before
+// drawing, GLRenderer uploads the textures it draws.
+func (t *Texture) Upload() {
+	if t.id != 0 || t.pix == nil {
+		return
+	}
+	gl.GenTextures(1, &t.id)
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, glMinFilter(t.options.MinFilter, t.options.Mipmaps))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, glFilters[t.options.MagFilter])
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, glWraps[t.options.WrapS])
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, glWraps[t.options.WrapT])
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, gl.Sizei(t.width), gl.Sizei(t.height), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Void(&t.pix[0]))
+	if t.options.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+}
+
+// Delete deletes the texture from the OpenGL context. Textures
+// holding their pixels are uploaded again if they are drawn
+// afterwards. Delete must be called from the thread owning the
+// context.
+func (t *Texture) Delete() {
+	if t.id != 0 {
+		gl.DeleteTextures(1, &t.id)
+		t.id = 0
+	}
+}
+
+// texel returns the normalized color of the texel nearest to the
+// texture coordinates (s, u) applying the wrap modes of the texture.
+// As in the default fragment shader the u coordinate is flipped.
+func (t *Texture) texel(s, u float32) [4]float32 {
+	x := wrapTexel(mathf.Floor(s*float32(t.width)), t.width, t.options.WrapS)
+	y := wrapTexel(mathf.Floor((1-u)*float32(t.height)), t.height, t.options.WrapT)
+	i := y*t.width*4 + x*4
+	return [4]float32{
+		float32(t.pix[i]) / 255,
+		float32(t.pix[i+1]) / 255,
+		float32(t.pix[i+2]) / 255,
+		float32(t.pix[i+3]) / 255,
+	}
+}
+
+// wrapTexel maps the texel coordinate v in the [0, size) range.
+func wrapTexel(v float32, size int, wrap TextureWrap) int {
+	i := int(v)
+	switch wrap {
+	case RepeatWrap:
+		i %= size
+		if i < 0 {
+			i += size
+		}
+	case MirroredRepeatWrap:
+		i %= 2 * size
+		if i < 0 {
+			i += 2 * size
+		}
+		if i >= size {
+			i = 2*size - 1 - i
+		}
+	default:
+		i = int(mathf.Clamp(v, 0, float32(size-1)))
+	}
+	return i
+}
+
+var glFilters = map[TextureFilter]int32{
+	NearestFilter: gl.NEAREST,
+	LinearFilter:  gl.LINEAR,
+}
+
+var glWraps = map[TextureWrap]int32{
+	ClampWrap:          gl.CLAMP_TO_EDGE,
+	RepeatWrap:         gl.REPEAT,
+	MirroredRepeatWrap: gl.MIRRORED_REPEAT,
+}
+
+// glMinFilter returns the minification filter, sampling the nearest
+// mipmap if mipmaps are enabled.
+func glMinFilter(f TextureFilter, mipmaps bool) int32 {
+	if !mipmaps {
+		return glFilters[f]
+	}
+	if f == LinearFilter {
+		return gl.LINEAR_MIPMAP_NEAREST
+	}
+	return gl.NEAREST_MIPMAP_NEAREST
+}
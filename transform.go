@@ -0,0 +1,66 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/remogatto/mathgl"
+)
+
+// Transform holds the position, rotation, scale and pivot of a
+// shape. The pivot, in shape coordinates, is the point placed at the
+// position and the point the shape rotates and scales around.
+type Transform struct {
+	// Position of the pivot
+	X, Y float32
+
+	// Angle in degrees
+	Angle float32
+
+	// Scale factors
+	ScaleX, ScaleY float32
+
+	// Pivot in shape coordinates
+	PivotX, PivotY float32
+}
+
+// NewTransform returns the identity transform.
+func NewTransform() Transform {
+	return Transform{ScaleX: 1, ScaleY: 1}
+}
+
+// Matrix returns the matrix applying, in order, the pivot
+// translation, the scale, the rotation and the translation to the
+// position.
+func (t Transform) Matrix() mathgl.Mat4f {
+	return mathgl.Translate3D(t.X, t.Y, 0).
+		Mul4(mathgl.HomogRotate3DZ(t.Angle)).
+		Mul4(mathgl.Scale3D(t.ScaleX, t.ScaleY, 1)).
+		Mul4(mathgl.Translate3D(-t.PivotX, -t.PivotY, 0))
+}
+
+// Apply transforms the point (x, y) from shape coordinates.
+func (t Transform) Apply(x, y float32) (float32, float32) {
+	x, y = (x-t.PivotX)*t.ScaleX, (y-t.PivotY)*t.ScaleY
+	x, y = rotate(x, y, t.Angle)
+	return x + t.X, y + t.Y
+}
+
+// rotate rotates the vector (x, y) by angle degrees.
+func rotate(x, y, angle float32) (float32, float32) {
+	if angle == 0 {
+		return x, y
+	}
+	sin, cos := math.Sincos(float64(angle) * math.Pi / 180)
+	s, c := float32(sin), float32(cos)
+	return x*c - y*s, x*s + y*c
+}
+
+// transformPoints returns the (x, y) points transformed by m.
+func transformPoints(m mathgl.Mat4f, points []float32) []float32 {
+	result := make([]float32, len(points))
+	for i := 0; i+1 < len(points); i += 2 {
+		v := m.Mul4x1(mathgl.Vec4f{points[i], points[i+1], 0, 1})
+		result[i], result[i+1] = v[0], v[1]
+	}
+	return result
+}
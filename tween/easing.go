@@ -0,0 +1,125 @@
+package tween
+
+import "math"
+
+// Easing maps the linear progress of a tween, from 0 to 1, to the
+// progress of the animated property. It must return 0 for 0 and 1
+// for 1, values in between may overshoot.
+type Easing func(t float32) float32
+
+// Linear progresses at constant speed.
+func Linear(t float32) float32 {
+	return t
+}
+
+// QuadIn accelerates from zero speed.
+func QuadIn(t float32) float32 {
+	return t * t
+}
+
+// QuadOut decelerates to zero speed.
+func QuadOut(t float32) float32 {
+	return t * (2 - t)
+}
+
+// QuadInOut accelerates until halfway, then decelerates.
+func QuadInOut(t float32) float32 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+// CubicIn accelerates from zero speed.
+func CubicIn(t float32) float32 {
+	return t * t * t
+}
+
+// CubicOut decelerates to zero speed.
+func CubicOut(t float32) float32 {
+	t--
+	return t*t*t + 1
+}
+
+// CubicInOut accelerates until halfway, then decelerates.
+func CubicInOut(t float32) float32 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return t*t*t/2 + 1
+}
+
+// SineIn accelerates following a sine curve.
+func SineIn(t float32) float32 {
+	return 1 - float32(math.Cos(float64(t)*math.Pi/2))
+}
+
+// SineOut decelerates following a sine curve.
+func SineOut(t float32) float32 {
+	return float32(math.Sin(float64(t) * math.Pi / 2))
+}
+
+// SineInOut accelerates and decelerates following a sine curve.
+func SineInOut(t float32) float32 {
+	return (1 - float32(math.Cos(float64(t)*math.Pi))) / 2
+}
+
+// ExpoIn accelerates exponentially.
+func ExpoIn(t float32) float32 {
+	if t == 0 {
+		return 0
+	}
+	return float32(math.Pow(2, 10*(float64(t)-1)))
+}
+
+// ExpoOut decelerates exponentially.
+func ExpoOut(t float32) float32 {
+	if t == 1 {
+		return 1
+	}
+	return 1 - float32(math.Pow(2, -10*float64(t)))
+}
+
+// backOvershoot controls how far the back easings overshoot.
+const backOvershoot = 1.70158
+
+// BackIn moves slightly backward before accelerating.
+func BackIn(t float32) float32 {
+	return t * t * ((backOvershoot+1)*t - backOvershoot)
+}
+
+// BackOut overshoots the end before settling.
+func BackOut(t float32) float32 {
+	t--
+	return t*t*((backOvershoot+1)*t+backOvershoot) + 1
+}
+
+// ElasticOut overshoots the end oscillating like a spring.
+func ElasticOut(t float32) float32 {
+	if t == 0 || t == 1 {
+		return t
+	}
+	return float32(math.Pow(2, -10*float64(t))*math.Sin((float64(t)-0.075)*2*math.Pi/0.3)) + 1
+}
+
+// BounceOut bounces against the end.
+func BounceOut(t float32) float32 {
+	switch {
+	case t < 1/2.75:
+		return 7.5625 * t * t
+	case t < 2/2.75:
+		t -= 1.5 / 2.75
+		return 7.5625*t*t + 0.75
+	case t < 2.5/2.75:
+		t -= 2.25 / 2.75
+		return 7.5625*t*t + 0.9375
+	}
+	t -= 2.625 / 2.75
+	return 7.5625*t*t + 0.984375
+}
+
+// BounceIn bounces against the start.
+func BounceIn(t float32) float32 {
+	return 1 - BounceOut(1-t)
+}
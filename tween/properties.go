@@ -0,0 +1,142 @@
+package tween
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/aded/shapes"
+)
+
+// Colored is implemented by the shapes whose color can be animated.
+type Colored interface {
+	Color() color.Color
+	SetColor(c color.Color)
+}
+
+// lerp interpolates linearly between a and b.
+func lerp(a, b, k float32) float32 {
+	return a + (b-a)*k
+}
+
+// MoveTo moves the center of the shape to (x, y), in the coordinates
+// of its parent.
+func MoveTo(s shapes.Shape, x, y float32, d time.Duration) *Tween {
+	var x0, y0 float32
+	return New(d,
+		func() { x0, y0 = s.Center() },
+		func(k float32) { s.MoveTo(lerp(x0, x, k), lerp(y0, y, k)) },
+	)
+}
+
+// MoveBy moves the shape by (dx, dy).
+func MoveBy(s shapes.Shape, dx, dy float32, d time.Duration) *Tween {
+	var x0, y0 float32
+	return New(d,
+		func() { x0, y0 = s.Center() },
+		func(k float32) { s.MoveTo(x0+dx*k, y0+dy*k) },
+	)
+}
+
+// RotateTo rotates the shape around its pivot to the given angle in
+// degrees.
+func RotateTo(s shapes.Shape, angle float32, d time.Duration) *Tween {
+	var a0 float32
+	return New(d,
+		func() { a0 = s.Transform().Angle },
+		func(k float32) {
+			t := s.Transform()
+			t.Angle = lerp(a0, angle, k)
+			s.SetTransform(t)
+		},
+	)
+}
+
+// RotateBy rotates the shape around its pivot by the given angle in
+// degrees.
+func RotateBy(s shapes.Shape, angle float32, d time.Duration) *Tween {
+	var a0 float32
+	return New(d,
+		func() { a0 = s.Transform().Angle },
+		func(k float32) {
+			t := s.Transform()
+			t.Angle = a0 + angle*k
+			s.SetTransform(t)
+		},
+	)
+}
+
+// ScaleTo scales the shape around its pivot to the given scale
+// factors.
+func ScaleTo(s shapes.Shape, sx, sy float32, d time.Duration) *Tween {
+	var sx0, sy0 float32
+	return New(d,
+		func() {
+			t := s.Transform()
+			sx0, sy0 = t.ScaleX, t.ScaleY
+		},
+		func(k float32) {
+			t := s.Transform()
+			t.ScaleX, t.ScaleY = lerp(sx0, sx, k), lerp(sy0, sy, k)
+			s.SetTransform(t)
+		},
+	)
+}
+
+// ColorTo changes the color of the shape to c, interpolating the
+// non-premultiplied components.
+func ColorTo(s Colored, c color.Color, d time.Duration) *Tween {
+	var c0 color.NRGBA
+	c1 := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return New(d,
+		func() { c0 = color.NRGBAModel.Convert(s.Color()).(color.NRGBA) },
+		func(k float32) {
+			s.SetColor(color.NRGBA{
+				lerpByte(c0.R, c1.R, k),
+				lerpByte(c0.G, c1.G, k),
+				lerpByte(c0.B, c1.B, k),
+				lerpByte(c0.A, c1.A, k),
+			})
+		},
+	)
+}
+
+// FadeTo changes the alpha of the color of the shape to alpha, from
+// 0 (transparent) to 1 (opaque).
+func FadeTo(s Colored, alpha float32, d time.Duration) *Tween {
+	var c0 color.NRGBA
+	return New(d,
+		func() { c0 = color.NRGBAModel.Convert(s.Color()).(color.NRGBA) },
+		func(k float32) {
+			c := c0
+			c.A = lerpByte(c0.A, uint8(clamp(alpha)*255+0.5), k)
+			s.SetColor(c)
+		},
+	)
+}
+
+// OpacityTo changes the opacity of the shape, and of its children if
+// the shape is a group.
+func OpacityTo(s shapes.Shape, opacity float32, d time.Duration) *Tween {
+	var o0 float32
+	return New(d,
+		func() { o0 = s.Opacity() },
+		func(k float32) { s.SetOpacity(lerp(o0, opacity, k)) },
+	)
+}
+
+// lerpByte interpolates between two color components, clamping the
+// overshooting easings.
+func lerpByte(a, b uint8, k float32) uint8 {
+	return uint8(clamp(lerp(float32(a), float32(b), k)/255)*255 + 0.5)
+}
+
+// clamp clamps v in the [0, 1] range.
+func clamp(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,186 @@
+package tween
+
+import "time"
+
+// Sequence plays its animations one after the other.
+type Sequence struct {
+	repeater
+
+	animations []Animation
+
+	// Index of the playing animation
+	current int
+	started bool
+}
+
+// NewSequence returns a sequence playing the animations in order.
+func NewSequence(animations ...Animation) *Sequence {
+	return &Sequence{animations: animations}
+}
+
+// Repeat sets the number of times the sequence is played again after
+// the first one, Forever to play it endlessly.
+func (s *Sequence) Repeat(n int) *Sequence {
+	s.repeat = n
+	return s
+}
+
+// OnStart sets a function called when the sequence starts.
+func (s *Sequence) OnStart(fn func()) *Sequence {
+	s.onStart = fn
+	return s
+}
+
+// OnComplete sets a function called when the last iteration of the
+// sequence ends.
+func (s *Sequence) OnComplete(fn func()) *Sequence {
+	s.onComplete = fn
+	return s
+}
+
+// Update advances the sequence by dt and returns true when the
+// sequence has completed.
+func (s *Sequence) Update(dt time.Duration) bool {
+	_, done := s.Advance(dt)
+	return done
+}
+
+// Advance advances the sequence by dt. It returns true when the
+// sequence has completed, and the part of dt not needed to complete
+// it.
+func (s *Sequence) Advance(dt time.Duration) (time.Duration, bool) {
+	if s.done {
+		return dt, true
+	}
+	if !s.started {
+		s.started = true
+		if s.onStart != nil {
+			s.onStart()
+		}
+	}
+	for {
+		start := dt
+		for s.current < len(s.animations) {
+			left, done := s.animations[s.current].Advance(dt)
+			if !done {
+				return 0, false
+			}
+			dt = left
+			s.current++
+		}
+		// Sequences taking no time complete at once
+		if dt == start && s.iteration > 0 {
+			s.finish()
+		}
+		if s.done || s.next() {
+			return dt, true
+		}
+		s.rewindAnimations()
+	}
+}
+
+// rewindAnimations starts a new iteration.
+func (s *Sequence) rewindAnimations() {
+	s.current = 0
+	for _, a := range s.animations {
+		a.Reset()
+	}
+}
+
+// Reset rewinds the sequence and its animations to their start.
+func (s *Sequence) Reset() {
+	s.rewind()
+	s.rewindAnimations()
+	s.started = false
+}
+
+// Parallel plays its animations at the same time. It completes when
+// all of them have completed.
+type Parallel struct {
+	repeater
+
+	animations []Animation
+	started    bool
+}
+
+// NewParallel returns a group playing the animations at the same
+// time.
+func NewParallel(animations ...Animation) *Parallel {
+	return &Parallel{animations: animations}
+}
+
+// Repeat sets the number of times the group is played again after
+// the first one, Forever to play it endlessly.
+func (p *Parallel) Repeat(n int) *Parallel {
+	p.repeat = n
+	return p
+}
+
+// OnStart sets a function called when the group starts.
+func (p *Parallel) OnStart(fn func()) *Parallel {
+	p.onStart = fn
+	return p
+}
+
+// OnComplete sets a function called when the last iteration of the
+// group ends.
+func (p *Parallel) OnComplete(fn func()) *Parallel {
+	p.onComplete = fn
+	return p
+}
+
+// Update advances the group by dt and returns true when the group
+// has completed.
+func (p *Parallel) Update(dt time.Duration) bool {
+	_, done := p.Advance(dt)
+	return done
+}
+
+// Advance advances the group by dt. It returns true when the group
+// has completed, and the part of dt not needed to complete it.
+func (p *Parallel) Advance(dt time.Duration) (time.Duration, bool) {
+	if p.done {
+		return dt, true
+	}
+	if !p.started {
+		p.started = true
+		if p.onStart != nil {
+			p.onStart()
+		}
+	}
+	for {
+		// The group completes when the longest animation does
+		left, completed := dt, true
+		for _, a := range p.animations {
+			l, done := a.Advance(dt)
+			if !done {
+				completed = false
+			} else if l < left {
+				left = l
+			}
+		}
+		if !completed {
+			return 0, false
+		}
+		// Groups taking no time complete at once
+		if left == dt && p.iteration > 0 {
+			p.finish()
+		}
+		if p.done || p.next() {
+			return left, true
+		}
+		for _, a := range p.animations {
+			a.Reset()
+		}
+		dt = left
+	}
+}
+
+// Reset rewinds the group and its animations to their start.
+func (p *Parallel) Reset() {
+	p.rewind()
+	for _, a := range p.animations {
+		a.Reset()
+	}
+	p.started = false
+}
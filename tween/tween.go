@@ -0,0 +1,248 @@
+// Package tween animates the properties of shapes over time.
+//
+// A Tween interpolates a value from its current state to a target
+// with an easing function. Tweens are combined in sequences and
+// parallel groups and advanced explicitly by Update, so animations
+// are deterministic:
+//
+//	m := new(tween.Manager)
+//	m.Add(tween.NewSequence(
+//		tween.MoveTo(box, 100, 0, time.Second).Ease(tween.QuadOut),
+//		tween.NewParallel(
+//			tween.RotateBy(box, 90, 500*time.Millisecond),
+//			tween.FadeTo(box, 0, 500*time.Millisecond),
+//		),
+//	).Repeat(tween.Forever))
+//	...
+//	m.Update(dt) // once per frame
+package tween
+
+import "time"
+
+// Forever repeats an animation endlessly.
+const Forever = -1
+
+// Animation is implemented by tweens, sequences and parallel groups.
+// Other types implementing it can be played by sequences, parallel
+// groups and managers too.
+type Animation interface {
+	// Update advances the animation by dt and returns true when
+	// the animation has completed.
+	Update(dt time.Duration) bool
+
+	// Advance advances the animation by dt. It returns true when
+	// the animation has completed, and the part of dt not needed
+	// to complete it, which sequences pass to the next animation.
+	Advance(dt time.Duration) (left time.Duration, done bool)
+
+	// Reset rewinds the animation to its start, to play it again.
+	Reset()
+}
+
+// repeater counts the iterations of an animation and calls its
+// callbacks.
+type repeater struct {
+	// Number of iterations after the first one, Forever for
+	// endless animations
+	repeat int
+
+	// Iterations completed
+	iteration int
+
+	done bool
+
+	onStart, onComplete func()
+}
+
+// next is called at the end of each iteration. It returns true if
+// the animation has completed.
+func (r *repeater) next() bool {
+	if r.repeat != Forever && r.iteration >= r.repeat {
+		r.finish()
+		return true
+	}
+	r.iteration++
+	return false
+}
+
+// finish completes the animation.
+func (r *repeater) finish() {
+	r.done = true
+	if r.onComplete != nil {
+		r.onComplete()
+	}
+}
+
+func (r *repeater) rewind() {
+	r.iteration = 0
+	r.done = false
+}
+
+// Tween interpolates a value over a duration.
+type Tween struct {
+	repeater
+
+	duration, delay time.Duration
+	easing          Easing
+	yoyo            bool
+
+	// Time elapsed in the current iteration, including the delay
+	// in the first one
+	elapsed time.Duration
+	started bool
+
+	// init captures the start values when the tween starts
+	init func()
+
+	// apply sets the value at the eased progress k
+	apply func(k float32)
+}
+
+// New returns a tween calling apply with the eased progress of the
+// tween, from 0 to 1, at each update. If init is not nil it's called
+// when the tween starts, after the delay, to capture the start
+// values.
+func New(duration time.Duration, init func(), apply func(k float32)) *Tween {
+	return &Tween{duration: duration, easing: Linear, init: init, apply: apply}
+}
+
+// Ease sets the easing function of the tween. The default is
+// Linear.
+func (t *Tween) Ease(easing Easing) *Tween {
+	t.easing = easing
+	return t
+}
+
+// Delay sets the time waited before the tween starts.
+func (t *Tween) Delay(d time.Duration) *Tween {
+	t.delay = d
+	return t
+}
+
+// Repeat sets the number of times the tween is played again after
+// the first one, Forever to play it endlessly.
+func (t *Tween) Repeat(n int) *Tween {
+	t.repeat = n
+	return t
+}
+
+// Yoyo plays the repeated iterations backward and forward
+// alternately.
+func (t *Tween) Yoyo(yoyo bool) *Tween {
+	t.yoyo = yoyo
+	return t
+}
+
+// OnStart sets a function called when the tween starts, after the
+// delay.
+func (t *Tween) OnStart(fn func()) *Tween {
+	t.onStart = fn
+	return t
+}
+
+// OnComplete sets a function called when the last iteration of the
+// tween ends.
+func (t *Tween) OnComplete(fn func()) *Tween {
+	t.onComplete = fn
+	return t
+}
+
+// Update advances the tween by dt and returns true when the tween has
+// completed.
+func (t *Tween) Update(dt time.Duration) bool {
+	_, done := t.Advance(dt)
+	return done
+}
+
+// Advance advances the tween by dt. It returns true when the tween
+// has completed, and the part of dt not needed to complete it.
+func (t *Tween) Advance(dt time.Duration) (time.Duration, bool) {
+	if t.done {
+		return dt, true
+	}
+	t.elapsed += dt
+	if !t.started {
+		if t.elapsed < t.delay {
+			return 0, false
+		}
+		t.elapsed -= t.delay
+		t.started = true
+		if t.init != nil {
+			t.init()
+		}
+		if t.onStart != nil {
+			t.onStart()
+		}
+	}
+	for {
+		if t.elapsed < t.duration {
+			t.apply(t.easing(t.progress(float32(t.elapsed) / float32(t.duration))))
+			return 0, false
+		}
+		t.apply(t.easing(t.progress(1)))
+		t.elapsed -= t.duration
+		// Tweens with no duration complete at once
+		if t.duration <= 0 {
+			t.finish()
+		}
+		if t.done || t.next() {
+			return t.elapsed, true
+		}
+	}
+}
+
+// progress returns the progress p of the current iteration, reversed
+// in the odd iterations of yoyo tweens.
+func (t *Tween) progress(p float32) float32 {
+	if t.yoyo && t.iteration%2 == 1 {
+		return 1 - p
+	}
+	return p
+}
+
+// Reset rewinds the tween to its start. Tweens of properties read
+// their start values again when they start.
+func (t *Tween) Reset() {
+	t.rewind()
+	t.elapsed = 0
+	t.started = false
+}
+
+// Manager updates a set of animations, discarding them when they
+// complete.
+type Manager struct {
+	animations []Animation
+}
+
+// Add adds the animation to the manager.
+func (m *Manager) Add(a Animation) {
+	m.animations = append(m.animations, a)
+}
+
+// Remove stops the animation, leaving the animated properties in
+// their current state.
+func (m *Manager) Remove(a Animation) {
+	for i, b := range m.animations {
+		if b == a {
+			m.animations = append(m.animations[:i], m.animations[i+1:]...)
+			return
+		}
+	}
+}
+
+// Len returns the number of running animations.
+func (m *Manager) Len() int {
+	return len(m.animations)
+}
+
+// Update advances all the animations by dt. Animations added by the
+// callbacks start at the next update.
+func (m *Manager) Update(dt time.Duration) {
+	running := m.animations
+	m.animations = nil
+	for _, a := range running {
+		if !a.Update(dt) {
+			m.animations = append(m.animations, a)
+		}
+	}
+}
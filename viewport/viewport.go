@@ -0,0 +1,280 @@
+// Package viewport maps a virtual resolution on windows of any size
+// and density.
+//
+// Shapes are sized in the units of the virtual resolution and the
+// Viewport, a shapes.World, scales them to the window following a
+// Policy. The viewport reacts to the mandala window events:
+//
+//	v := viewport.New(480, 800, viewport.Letterbox)
+//	box.AttachToWorld(v)
+//	...
+//	case event := <-mandala.Events():
+//		v.HandleEvent(event)
+//	...
+//	v.Apply() // before drawing
+package viewport
+
+import (
+	"image"
+
+	"github.com/aded/shapes"
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mandala"
+	"github.com/remogatto/mathgl"
+	gl "github.com/remogatto/opengles2"
+)
+
+// Policy is the way the virtual resolution is scaled to the window.
+type Policy int
+
+const (
+	// Letterbox scales the virtual resolution uniformly to fit
+	// the window, leaving bars on two sides where nothing is
+	// drawn.
+	Letterbox Policy = iota
+
+	// Fit scales the virtual resolution uniformly to fit the
+	// window, showing more of the world on two sides.
+	Fit
+
+	// Fill scales the virtual resolution uniformly to cover the
+	// window, cropping two sides.
+	Fill
+
+	// Stretch scales the virtual resolution to the window,
+	// distorting the shapes if the aspect ratios differ.
+	Stretch
+)
+
+// BaseDensity is the density, in dots per inch, of a screen where a
+// density-independent unit is one pixel.
+const BaseDensity = 160
+
+// Viewport is a World showing a virtual resolution in a window. The
+// origin of the world is at the center of the window and the y axis
+// points up.
+type Viewport struct {
+	// Virtual resolution, zero to use the pixels of the window
+	virtualWidth, virtualHeight float32
+
+	policy Policy
+
+	// Pixels per density-independent unit
+	density float32
+
+	// Window size in pixels
+	width, height int
+
+	// Pixels per world unit
+	scaleX, scaleY float32
+
+	// Area of the window where the world is drawn, with the
+	// origin in the top-left corner
+	rect image.Rectangle
+
+	// Optional camera moving in the world
+	camera *shapes.Camera2D
+}
+
+// New returns a viewport scaling the virtual resolution following the
+// policy. With a zero virtual resolution world units are pixels of
+// the window. The viewport has no size until it's resized.
+func New(virtualWidth, virtualHeight float32, policy Policy) *Viewport {
+	return &Viewport{
+		virtualWidth:  virtualWidth,
+		virtualHeight: virtualHeight,
+		policy:        policy,
+		density:       1,
+		scaleX:        1,
+		scaleY:        1,
+	}
+}
+
+// HandleEvent resizes the viewport to the window carried by the
+// mandala events creating or redrawing it. Other events are ignored.
+func (v *Viewport) HandleEvent(event interface{}) {
+	switch e := event.(type) {
+	case mandala.NativeWindowCreatedEvent:
+		v.Resize(e.Window.GetSize())
+	case mandala.NativeWindowRedrawNeededEvent:
+		v.Resize(e.Window.GetSize())
+	}
+}
+
+// Resize sets the size of the window in pixels.
+func (v *Viewport) Resize(width, height int) {
+	v.width, v.height = width, height
+	v.update()
+}
+
+// Size returns the size of the window in pixels.
+func (v *Viewport) Size() (int, int) {
+	return v.width, v.height
+}
+
+// VirtualSize returns the virtual resolution.
+func (v *Viewport) VirtualSize() (float32, float32) {
+	return v.virtualWidth, v.virtualHeight
+}
+
+// SetVirtualSize sets the virtual resolution.
+func (v *Viewport) SetVirtualSize(width, height float32) {
+	v.virtualWidth, v.virtualHeight = width, height
+	v.update()
+}
+
+// Policy returns the scaling policy of the viewport.
+func (v *Viewport) Policy() Policy {
+	return v.policy
+}
+
+// SetPolicy sets the scaling policy of the viewport.
+func (v *Viewport) SetPolicy(policy Policy) {
+	v.policy = policy
+	v.update()
+}
+
+// Density returns the number of pixels per density-independent
+// unit.
+func (v *Viewport) Density() float32 {
+	return v.density
+}
+
+// SetDensity sets the number of pixels per density-independent unit,
+// usually the dots per inch of the screen divided by BaseDensity.
+// Densities not greater than 0 are ignored.
+func (v *Viewport) SetDensity(density float32) {
+	if density > 0 {
+		v.density = density
+	}
+}
+
+// Dp converts a length in density-independent units to world units
+// along the x axis, so that shapes have the same physical size on
+// every screen. The zoom of the camera is not taken into account.
+// Only the Stretch policy scales the axes differently, there lengths
+// along the y axis are length*Density()/sy, with sy from Scale.
+func (v *Viewport) Dp(length float32) float32 {
+	return length * v.density / v.scaleX
+}
+
+// Scale returns the number of pixels per world unit along the axes.
+func (v *Viewport) Scale() (float32, float32) {
+	return v.scaleX, v.scaleY
+}
+
+// PixelScale returns the number of pixels per world unit, including
+// the zoom of the camera. It implements shapes.PixelScaler.
+func (v *Viewport) PixelScale() float32 {
+	s := mathf.Max(v.scaleX, v.scaleY)
+	if v.camera != nil {
+		s *= v.camera.Zoom()
+	}
+	return s
+}
+
+// Rect returns the area of the window where the world is drawn, with
+// the origin in the top-left corner. It differs from the whole
+// window only with the Letterbox policy.
+func (v *Viewport) Rect() image.Rectangle {
+	return v.rect
+}
+
+// VisibleSize returns the size of the visible area in world units,
+// without the zoom of the camera.
+func (v *Viewport) VisibleSize() (float32, float32) {
+	return float32(v.rect.Dx()) / v.scaleX, float32(v.rect.Dy()) / v.scaleY
+}
+
+// Camera returns the camera moving in the world, nil if the view is
+// fixed.
+func (v *Viewport) Camera() *shapes.Camera2D {
+	return v.camera
+}
+
+// SetCamera sets a camera moving in the world, nil to fix the view.
+// The viewport of the camera is kept to the visible size, so its
+// bounds apply to the visible area.
+func (v *Viewport) SetCamera(camera *shapes.Camera2D) {
+	v.camera = camera
+	v.update()
+}
+
+// Apply sets the OpenGL viewport to the area of the window where the
+// world is drawn.
+func (v *Viewport) Apply() {
+	gl.Viewport(
+		int32(v.rect.Min.X), int32(v.height-v.rect.Max.Y),
+		gl.Sizei(v.rect.Dx()), gl.Sizei(v.rect.Dy()),
+	)
+}
+
+// Projection returns the orthographic projection of the visible area
+// on the OpenGL viewport.
+func (v *Viewport) Projection() mathgl.Mat4f {
+	w, h := v.VisibleSize()
+	return mathgl.Ortho2D(-w/2, w/2, -h/2, h/2)
+}
+
+// View returns the view matrix of the camera, the identity if there
+// is no camera.
+func (v *Viewport) View() mathgl.Mat4f {
+	if v.camera == nil {
+		return mathgl.Ident4f()
+	}
+	return v.camera.View()
+}
+
+// ScreenToWorld converts the point (x, y), in pixels of the window
+// with the origin in the top-left corner, to world coordinates.
+func (v *Viewport) ScreenToWorld(x, y float32) (float32, float32) {
+	// Units of the visible area, with the origin in the top-left
+	// corner
+	x, y = (x-float32(v.rect.Min.X))/v.scaleX, (y-float32(v.rect.Min.Y))/v.scaleY
+	if v.camera != nil {
+		return v.camera.ScreenToWorld(x, y)
+	}
+	w, h := v.VisibleSize()
+	return x - w/2, h/2 - y
+}
+
+// WorldToScreen converts the point (x, y), in world coordinates, to
+// pixels of the window with the origin in the top-left corner.
+func (v *Viewport) WorldToScreen(x, y float32) (float32, float32) {
+	if v.camera != nil {
+		x, y = v.camera.WorldToScreen(x, y)
+	} else {
+		w, h := v.VisibleSize()
+		x, y = x+w/2, h/2-y
+	}
+	return float32(v.rect.Min.X) + x*v.scaleX, float32(v.rect.Min.Y) + y*v.scaleY
+}
+
+// update computes the scale and the drawn area from the window size,
+// the virtual resolution and the policy.
+func (v *Viewport) update() {
+	v.rect = image.Rect(0, 0, v.width, v.height)
+	v.scaleX, v.scaleY = 1, 1
+	if v.virtualWidth > 0 && v.virtualHeight > 0 && v.width > 0 && v.height > 0 {
+		sx := float32(v.width) / v.virtualWidth
+		sy := float32(v.height) / v.virtualHeight
+		switch v.policy {
+		case Letterbox:
+			s := mathf.Min(sx, sy)
+			w, h := int(v.virtualWidth*s+0.5), int(v.virtualHeight*s+0.5)
+			x, y := (v.width-w)/2, (v.height-h)/2
+			v.rect = image.Rect(x, y, x+w, y+h)
+			v.scaleX, v.scaleY = s, s
+		case Fit:
+			v.scaleX, v.scaleY = mathf.Min(sx, sy), mathf.Min(sx, sy)
+		case Fill:
+			v.scaleX, v.scaleY = mathf.Max(sx, sy), mathf.Max(sx, sy)
+		case Stretch:
+			v.scaleX, v.scaleY = sx, sy
+		}
+	}
+	if v.camera != nil {
+		w, h := v.VisibleSize()
+		v.camera.SetViewport(w, h)
+	}
+}
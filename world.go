@@ -1,6 +1,11 @@
 package shapes
 
-import "github.com/remogatto/mathgl"
+import (
+	"math"
+
+	"github.com/aded/shapes/internal/mathf"
+	"github.com/remogatto/mathgl"
+)
 
 // World in an interface for projection and view matrix.
 type World interface {
@@ -12,3 +17,68 @@ type World interface {
 	// the point-of-view of a camera.
 	View() mathgl.Mat4f
 }
+
+// PixelScaler is implemented by the worlds whose units aren't pixels
+// of the window, e.g. virtual resolutions. Shapes approximated by
+// polygons use it to choose their level of detail.
+type PixelScaler interface {
+	// PixelScale returns the number of pixels of the window per
+	// world unit, including the zoom of the view.
+	PixelScale() float32
+}
+
+// pixelScale returns the number of pixels per world unit of the
+// world. Unless the world implements PixelScaler, the projection is
+// assumed to map world units to pixels and the scale is the one of
+// the view, e.g. the zoom of a Camera2D.
+func pixelScale(world World) float32 {
+	if world == nil {
+		return 1
+	}
+	if s, ok := world.(PixelScaler); ok {
+		return s.PixelScale()
+	}
+	v := world.View()
+	sx := math.Hypot(float64(v[0]), float64(v[1]))
+	sy := math.Hypot(float64(v[4]), float64(v[5]))
+	return mathf.Max(float32(sx), float32(sy))
+}
+
+// ScreenToWorld converts the point (x, y), in pixels of a window of
+// the given size with the origin in the top-left corner, to world
+// coordinates.
+func ScreenToWorld(world World, x, y float32, width, height int) (float32, float32) {
+	m := world.Projection().Mul4(world.View())
+	if m.Det() == 0 {
+		return 0, 0
+	}
+	ndc := mathgl.Vec4f{2*x/float32(width) - 1, 1 - 2*y/float32(height), 0, 1}
+	p := m.Inv().Mul4x1(ndc)
+	return p[0] / p[3], p[1] / p[3]
+}
+
+// WorldToScreen converts the point (x, y), in world coordinates, to
+// pixels of a window of the given size with the origin in the
+// top-left corner.
+func WorldToScreen(world World, x, y float32, width, height int) (float32, float32) {
+	p := world.Projection().Mul4(world.View()).Mul4x1(mathgl.Vec4f{x, y, 0, 1})
+	nx, ny := p[0]/p[3], p[1]/p[3]
+	return (nx + 1) / 2 * float32(width), (1 - ny) / 2 * float32(height)
+}
+
+// VisibleRect returns the smallest rectangle, in world coordinates,
+// containing the area of the world shown on the screen. It returns
+// false if the matrices of the world can't be inverted.
+func VisibleRect(world World) (Rect, bool) {
+	m := world.Projection().Mul4(world.View())
+	if m.Det() == 0 {
+		return Rect{}, false
+	}
+	inv := m.Inv()
+	corners := make([]float32, 0, 8)
+	for _, c := range [][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		p := inv.Mul4x1(mathgl.Vec4f{c[0], c[1], 0, 1})
+		corners = append(corners, p[0]/p[3], p[1]/p[3])
+	}
+	return rectOf(corners), true
+}